@@ -1,11 +1,20 @@
 package main
 
-import "github.com/nrhvyc/go-voxel/game"
+import (
+	"flag"
+
+	"github.com/nrhvyc/go-voxel/game"
+)
 
 func main() {
-	engine, err := game.NewEngine()
+	worldDir := flag.String("world", "world", "directory of the world to open or create")
+	flag.Parse()
+
+	engine, err := game.NewEngine(*worldDir)
 	if err != nil {
 		panic(err)
 	}
-	engine.Run()
+	if err := engine.Run(); err != nil {
+		panic(err)
+	}
 }
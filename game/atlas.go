@@ -0,0 +1,195 @@
+package game
+
+import (
+	"cmp"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+)
+
+// generatedTileSize is the size of the tiles generated for block colors
+const generatedTileSize = 16
+
+// TextureAtlas is a single image packed with named textures
+type TextureAtlas struct {
+	Image *image.RGBA
+	Rects map[string]image.Rectangle
+}
+
+// PackAtlas packs the images into rows on a power of two sized atlas, with
+// padding pixels around each. Images are placed tallest first then by name,
+// so the same images always give the same layout
+func PackAtlas(images map[string]image.Image, padding int) *TextureAtlas {
+	names := make([]string, 0, len(images))
+	area, widest := 0, 0
+	for name, img := range images {
+		names = append(names, name)
+		size := img.Bounds().Size()
+		area += (size.X + padding*2) * (size.Y + padding*2)
+		widest = max(widest, size.X+padding*2)
+	}
+	slices.SortFunc(names, func(a, b string) int {
+		if c := cmp.Compare(images[b].Bounds().Dy(), images[a].Bounds().Dy()); c != 0 {
+			return c
+		}
+		return cmp.Compare(a, b)
+	})
+
+	// Start with a square that could just about hold everything
+	width := 1
+	for width < widest || width*width < area {
+		width *= 2
+	}
+
+	rects := make(map[string]image.Rectangle, len(names))
+	x, y, rowHeight := 0, 0, 0
+	for _, name := range names {
+		size := images[name].Bounds().Size()
+		w, h := size.X+padding*2, size.Y+padding*2
+
+		if x+w > width {
+			x, y = 0, y+rowHeight
+			rowHeight = 0
+		}
+
+		min := image.Pt(x+padding, y+padding)
+		rects[name] = image.Rectangle{Min: min, Max: min.Add(size)}
+
+		x += w
+		rowHeight = max(rowHeight, h)
+	}
+
+	height := 1
+	for height < y+rowHeight {
+		height *= 2
+	}
+
+	atlas := &TextureAtlas{
+		Image: image.NewRGBA(image.Rect(0, 0, width, height)),
+		Rects: rects,
+	}
+	for name, rect := range rects {
+		img := images[name]
+		draw.Draw(atlas.Image, rect, img, img.Bounds().Min, draw.Src)
+	}
+
+	return atlas
+}
+
+// UV returns the normalized texture coordinates of the named texture
+func (a *TextureAtlas) UV(name string) (u0, v0, u1, v1 float32, ok bool) {
+	rect, ok := a.Rects[name]
+	if !ok {
+		return 0, 0, 0, 0, false
+	}
+
+	size := a.Image.Bounds().Size()
+	return float32(rect.Min.X) / float32(size.X),
+		float32(rect.Min.Y) / float32(size.Y),
+		float32(rect.Max.X) / float32(size.X),
+		float32(rect.Max.Y) / float32(size.Y),
+		true
+}
+
+// WritePNG encodes the atlas image as a PNG
+func (a *TextureAtlas) WritePNG(w io.Writer) error {
+	return png.Encode(w, a.Image)
+}
+
+// blockFace is which way a face of a block points, for picking its texture
+type blockFace int
+
+const (
+	faceTop blockFace = iota
+	faceSide
+	faceBottom
+)
+
+// BlockAtlas is the atlas of every block texture, with the UV rectangle of
+// each face of each block
+type BlockAtlas struct {
+	*TextureAtlas
+
+	faces map[VoxelType][3][4]float32
+}
+
+// LoadBlockAtlas packs the textures of every block into an atlas. Textures
+// are PNG files in dir, and faces without one get a tile of the block color
+func LoadBlockAtlas(blocks *BlockRegistry, dir string) (*BlockAtlas, error) {
+	images := make(map[string]image.Image)
+	faceTiles := make(map[VoxelType][3]string)
+
+	for _, t := range blocks.Types() {
+		block, _ := blocks.Get(t)
+
+		var tiles [3]string
+		for face, texture := range block.Textures.faces() {
+			if texture == "" {
+				tiles[face] = fmt.Sprintf("color:%02x%02x%02x%02x",
+					block.Color.R, block.Color.G, block.Color.B, block.Color.A)
+				if _, ok := images[tiles[face]]; !ok {
+					images[tiles[face]] = solidTile(block.Color)
+				}
+				continue
+			}
+
+			tiles[face] = texture
+			if _, ok := images[texture]; ok {
+				continue
+			}
+			img, err := loadPNG(filepath.Join(dir, texture))
+			if err != nil {
+				return nil, fmt.Errorf("block %q: %w", block.Name, err)
+			}
+			images[texture] = img
+		}
+		faceTiles[t] = tiles
+	}
+
+	atlas := &BlockAtlas{
+		TextureAtlas: PackAtlas(images, 0),
+		faces:        make(map[VoxelType][3][4]float32),
+	}
+	for t, tiles := range faceTiles {
+		var uvs [3][4]float32
+		for face, tile := range tiles {
+			u0, v0, u1, v1, _ := atlas.UV(tile)
+			uvs[face] = [4]float32{u0, v0, u1, v1}
+		}
+		atlas.faces[t] = uvs
+	}
+
+	return atlas, nil
+}
+
+// faceUV returns the UV rectangle (u0, v0, u1, v1) for the face of the type.
+// Unknown types get an empty rectangle
+func (a *BlockAtlas) faceUV(t VoxelType, face blockFace) [4]float32 {
+	return a.faces[t][face]
+}
+
+func solidTile(c color.RGBA) image.Image {
+	tile := image.NewRGBA(image.Rect(0, 0, generatedTileSize, generatedTileSize))
+	draw.Draw(tile, tile.Bounds(), image.NewUniform(c), image.Point{}, draw.Src)
+	return tile
+}
+
+func loadPNG(path string) (image.Image, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("opening texture: %w", err)
+	}
+	defer file.Close()
+
+	img, err := png.Decode(file)
+	if err != nil {
+		return nil, fmt.Errorf("decoding texture %s: %w", path, err)
+	}
+	return img, nil
+}
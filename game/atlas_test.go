@@ -0,0 +1,236 @@
+package game
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testImages are solid images of assorted sizes, each a different color
+func testImages() map[string]image.Image {
+	sizes := map[string]image.Point{
+		"wide":   {40, 8},
+		"tall":   {8, 30},
+		"square": {16, 16},
+		"small":  {5, 5},
+		"other":  {16, 16},
+	}
+	images := make(map[string]image.Image)
+	i := 0
+	for name, size := range sizes {
+		img := image.NewRGBA(image.Rectangle{Max: size})
+		for p := range size.X * size.Y {
+			img.Set(p%size.X, p/size.X, color.RGBA{uint8(40 * i), 100, uint8(p), 255})
+		}
+		images[name] = img
+		i++
+	}
+	return images
+}
+
+func TestPackAtlas(t *testing.T) {
+	const padding = 2
+	images := testImages()
+	atlas := PackAtlas(images, padding)
+
+	size := atlas.Image.Bounds().Size()
+	if size.X&(size.X-1) != 0 || size.Y&(size.Y-1) != 0 {
+		t.Errorf("atlas is %v, want power of two sides", size)
+	}
+	if len(atlas.Rects) != len(images) {
+		t.Fatalf("packed %d of %d images", len(atlas.Rects), len(images))
+	}
+
+	// Tallest first, along the top row
+	if got := atlas.Rects["tall"].Min; got != image.Pt(padding, padding) {
+		t.Errorf("tallest image is at %v, want %v", got, image.Pt(padding, padding))
+	}
+
+	for name, rect := range atlas.Rects {
+		img := images[name]
+		if rect.Size() != img.Bounds().Size() {
+			t.Errorf("%s is packed at %v, want size %v", name, rect, img.Bounds().Size())
+		}
+
+		// The padding around each image stays inside the atlas and clear
+		// of every other image
+		padded := rect.Inset(-padding)
+		if !padded.In(atlas.Image.Bounds()) {
+			t.Errorf("%s padded to %v is outside the atlas %v", name, padded, atlas.Image.Bounds())
+		}
+		for other, otherRect := range atlas.Rects {
+			if other != name && padded.Overlaps(otherRect) {
+				t.Errorf("%s padded to %v overlaps %s at %v", name, padded, other, otherRect)
+			}
+		}
+
+		for y := range rect.Dy() {
+			for x := range rect.Dx() {
+				if got, want := atlas.Image.At(rect.Min.X+x, rect.Min.Y+y), img.At(x, y); got != want {
+					t.Fatalf("%s pixel (%d, %d) = %v, want %v", name, x, y, got, want)
+				}
+			}
+		}
+	}
+
+	// The same images always pack the same way
+	again := PackAtlas(images, padding)
+	for name, rect := range atlas.Rects {
+		if again.Rects[name] != rect {
+			t.Errorf("%s packed at %v then %v", name, rect, again.Rects[name])
+		}
+	}
+}
+
+func TestPackAtlasOneImage(t *testing.T) {
+	img := solidTile(color.RGBA{1, 2, 3, 255})
+	atlas := PackAtlas(map[string]image.Image{"tile": img}, 0)
+
+	if got := atlas.Image.Bounds(); got != image.Rect(0, 0, generatedTileSize, generatedTileSize) {
+		t.Errorf("atlas of one tile is %v", got)
+	}
+	u0, v0, u1, v1, ok := atlas.UV("tile")
+	if !ok || u0 != 0 || v0 != 0 || u1 != 1 || v1 != 1 {
+		t.Errorf("UV = %v, %v, %v, %v, %v, want the whole atlas", u0, v0, u1, v1, ok)
+	}
+	if _, _, _, _, ok := atlas.UV("missing"); ok {
+		t.Error("UV of a missing texture is ok")
+	}
+}
+
+func TestAtlasUV(t *testing.T) {
+	atlas := PackAtlas(testImages(), 1)
+	size := atlas.Image.Bounds().Size()
+
+	for name, rect := range atlas.Rects {
+		u0, v0, u1, v1, ok := atlas.UV(name)
+		if !ok {
+			t.Fatalf("no UV for %s", name)
+		}
+		got := [4]float32{u0 * float32(size.X), v0 * float32(size.Y), u1 * float32(size.X), v1 * float32(size.Y)}
+		want := [4]float32{float32(rect.Min.X), float32(rect.Min.Y), float32(rect.Max.X), float32(rect.Max.Y)}
+		if got != want {
+			t.Errorf("UV of %s is %v in pixels, want %v", name, got, want)
+		}
+	}
+}
+
+func TestAtlasWritePNG(t *testing.T) {
+	atlas := PackAtlas(testImages(), 1)
+
+	var buf bytes.Buffer
+	if err := atlas.WritePNG(&buf); err != nil {
+		t.Fatal(err)
+	}
+	img, err := png.Decode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bounds := atlas.Image.Bounds()
+	if img.Bounds() != bounds {
+		t.Fatalf("decoded atlas is %v, want %v", img.Bounds(), bounds)
+	}
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			if got, want := color.RGBAModel.Convert(img.At(x, y)), atlas.Image.At(x, y); got != want {
+				t.Fatalf("decoded pixel (%d, %d) = %v, want %v", x, y, got, want)
+			}
+		}
+	}
+}
+
+// writeTestPNG writes a solid image to the path
+func writeTestPNG(t *testing.T, path string, size int, c color.RGBA) {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, size, size))
+	for i := range size * size {
+		img.Set(i%size, i/size, c)
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadBlockAtlas(t *testing.T) {
+	dir := t.TempDir()
+	writeTestPNG(t, filepath.Join(dir, "log.png"), 32, color.RGBA{120, 80, 40, 255})
+	writeTestPNG(t, filepath.Join(dir, "log_top.png"), 16, color.RGBA{200, 170, 100, 255})
+
+	blocks, err := LoadBlockRegistry(strings.NewReader(`[
+		{"name": "log", "id": 1, "texture": "log.png", "textures": {"top": "log_top.png", "bottom": "log_top.png"}, "solid": true},
+		{"name": "sand", "id": 2, "color": "#e0d090", "solid": true},
+		{"name": "grassy", "id": 3, "color": "#e0d090", "textures": {"top": "log_top.png"}, "solid": true}
+	]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	atlas, err := LoadBlockAtlas(blocks, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Shared textures and colors are only packed once
+	if len(atlas.Rects) != 3 {
+		t.Errorf("packed %d textures, want log.png, log_top.png and one color tile", len(atlas.Rects))
+	}
+
+	tests := []struct {
+		t     VoxelType
+		face  blockFace
+		tile  string
+		color color.RGBA
+	}{
+		{1, faceTop, "log_top.png", color.RGBA{200, 170, 100, 255}},
+		{1, faceSide, "log.png", color.RGBA{120, 80, 40, 255}},
+		{1, faceBottom, "log_top.png", color.RGBA{200, 170, 100, 255}},
+		{2, faceSide, "color:e0d090ff", color.RGBA{0xe0, 0xd0, 0x90, 255}},
+		{3, faceTop, "log_top.png", color.RGBA{200, 170, 100, 255}},
+		{3, faceBottom, "color:e0d090ff", color.RGBA{0xe0, 0xd0, 0x90, 255}},
+	}
+	size := atlas.Image.Bounds().Size()
+	for _, test := range tests {
+		u0, v0, u1, v1, ok := atlas.UV(test.tile)
+		if !ok {
+			t.Fatalf("%s isn't in the atlas", test.tile)
+		}
+		if got := atlas.faceUV(test.t, test.face); got != [4]float32{u0, v0, u1, v1} {
+			t.Errorf("face %d of block %d has UV %v, want %s", test.face, test.t, got, test.tile)
+		}
+
+		// Sample the middle of the face's rectangle
+		x, y := int((u0+u1)/2*float32(size.X)), int((v0+v1)/2*float32(size.Y))
+		if got := atlas.Image.At(x, y); got != test.color {
+			t.Errorf("face %d of block %d is %v, want %v", test.face, test.t, got, test.color)
+		}
+	}
+
+	if got := atlas.faceUV(9, faceTop); got != [4]float32{} {
+		t.Errorf("unknown block has UV %v, want empty", got)
+	}
+}
+
+func TestLoadBlockAtlasMissingTexture(t *testing.T) {
+	blocks, err := LoadBlockRegistry(strings.NewReader(`[
+		{"name": "ore", "id": 1, "texture": "ore.png", "solid": true}
+	]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = LoadBlockAtlas(blocks, t.TempDir())
+	if err == nil || !strings.Contains(err.Error(), `block "ore"`) {
+		t.Errorf("LoadBlockAtlas = %v, want an error naming the block", err)
+	}
+}
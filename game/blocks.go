@@ -0,0 +1,315 @@
+package game
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image/color"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+//go:embed blocks.json
+var defaultBlocksJSON []byte
+
+// BlockDefinition describes a type of voxel
+type BlockDefinition struct {
+	Name string
+	ID   VoxelType
+
+	// Color is used for faces without a texture, and as the block's color
+	// when exporting to colored voxel formats
+	Color    color.RGBA
+	Textures BlockTextures
+
+	// Solid blocks collide with the player
+	Solid bool
+	// Transparent blocks don't hide the faces of the blocks behind them
+	Transparent bool
+
+	LightEmission uint8
+	Hardness      float64
+}
+
+// BlockTextures are the texture files for each face of a block, relative to
+// the textures directory. Empty faces use the block color
+type BlockTextures struct {
+	Top    string `json:"top"`
+	Side   string `json:"side"`
+	Bottom string `json:"bottom"`
+}
+
+// faces returns the textures indexed by blockFace
+func (t BlockTextures) faces() [3]string {
+	return [3]string{faceTop: t.Top, faceSide: t.Side, faceBottom: t.Bottom}
+}
+
+// blockDefinitionJSON is a BlockDefinition as written in the definitions
+// file. Pointers tell missing fields apart from zero values
+type blockDefinitionJSON struct {
+	Name          *string        `json:"name"`
+	ID            *int           `json:"id"`
+	Color         *string        `json:"color"`
+	Texture       *string        `json:"texture"`  // every face
+	Textures      *BlockTextures `json:"textures"` // overrides per face
+	Solid         *bool          `json:"solid"`
+	Transparent   *bool          `json:"transparent"`
+	LightEmission *int           `json:"light_emission"`
+	Hardness      *float64       `json:"hardness"`
+}
+
+// BlockRegistry holds every block definition by ID. It's read only once
+// loaded, so it's safe to share between goroutines
+type BlockRegistry struct {
+	blocks map[VoxelType]*BlockDefinition
+	byName map[string]*BlockDefinition
+}
+
+// DefaultBlockRegistry returns the built in block definitions
+func DefaultBlockRegistry() *BlockRegistry {
+	registry, err := LoadBlockRegistry(strings.NewReader(string(defaultBlocksJSON)))
+	if err != nil {
+		panic(fmt.Sprintf("built in block definitions: %v", err))
+	}
+	return registry
+}
+
+// LoadBlockRegistryFile reads block definitions from a JSON file
+func LoadBlockRegistryFile(path string) (*BlockRegistry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("opening block definitions: %w", err)
+	}
+	defer file.Close()
+
+	return LoadBlockRegistry(file)
+}
+
+// LoadBlockRegistry reads a JSON array of block definitions, reporting every
+// invalid definition at once. There must be at least one definition
+func LoadBlockRegistry(r io.Reader) (*BlockRegistry, error) {
+	var raw []blockDefinitionJSON
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&raw); err != nil {
+		return nil, fmt.Errorf("parsing block definitions: %w", err)
+	}
+	if len(raw) == 0 {
+		return nil, errors.New("no block definitions")
+	}
+
+	registry := &BlockRegistry{
+		blocks: make(map[VoxelType]*BlockDefinition),
+		byName: make(map[string]*BlockDefinition),
+	}
+
+	var errs []error
+	for i, def := range raw {
+		block, defErrs := def.validate()
+		for _, err := range defErrs {
+			errs = append(errs, fmt.Errorf("block %d: %w", i, err))
+		}
+		if len(defErrs) > 0 {
+			continue
+		}
+
+		if existing, ok := registry.blocks[block.ID]; ok {
+			errs = append(errs, fmt.Errorf("block %d: id %d is already used by %q", i, block.ID, existing.Name))
+			continue
+		}
+		if _, ok := registry.byName[block.Name]; ok {
+			errs = append(errs, fmt.Errorf("block %d: name %q is already used", i, block.Name))
+			continue
+		}
+
+		registry.blocks[block.ID] = block
+		registry.byName[block.Name] = block
+	}
+
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return registry, nil
+}
+
+// validate checks the required fields are present and converts the
+// definition, returning every problem with it
+func (def blockDefinitionJSON) validate() (*BlockDefinition, []error) {
+	var errs []error
+	missing := func(field string) {
+		errs = append(errs, fmt.Errorf("missing %s", field))
+	}
+
+	block := &BlockDefinition{}
+
+	if def.Name == nil || *def.Name == "" {
+		missing("name")
+	} else {
+		block.Name = *def.Name
+	}
+
+	switch {
+	case def.ID == nil:
+		missing("id")
+	case *def.ID == int(air):
+		errs = append(errs, fmt.Errorf("id %d is reserved for air", air))
+	case *def.ID < 0 || *def.ID > 0xffff:
+		errs = append(errs, fmt.Errorf("id %d is out of range", *def.ID))
+	default:
+		block.ID = VoxelType(*def.ID)
+	}
+
+	if def.Texture != nil {
+		block.Textures = BlockTextures{Top: *def.Texture, Side: *def.Texture, Bottom: *def.Texture}
+	}
+	if def.Textures != nil {
+		if def.Textures.Top != "" {
+			block.Textures.Top = def.Textures.Top
+		}
+		if def.Textures.Side != "" {
+			block.Textures.Side = def.Textures.Side
+		}
+		if def.Textures.Bottom != "" {
+			block.Textures.Bottom = def.Textures.Bottom
+		}
+	}
+
+	// Every face needs either a texture or the color to fall back on
+	if def.Color != nil {
+		c, err := parseHexColor(*def.Color)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		block.Color = c
+	} else if faces := block.Textures.faces(); slices.Contains(faces[:], "") {
+		missing("color or texture")
+	}
+
+	if def.Solid == nil {
+		missing("solid")
+	} else {
+		block.Solid = *def.Solid
+	}
+
+	if def.Transparent != nil {
+		block.Transparent = *def.Transparent
+	}
+
+	if def.LightEmission != nil {
+		if *def.LightEmission < 0 || *def.LightEmission > 15 {
+			errs = append(errs, fmt.Errorf("light_emission %d is out of range 0-15", *def.LightEmission))
+		}
+		block.LightEmission = uint8(*def.LightEmission)
+	}
+
+	if def.Hardness != nil {
+		if *def.Hardness < 0 {
+			errs = append(errs, fmt.Errorf("hardness %g is negative", *def.Hardness))
+		}
+		block.Hardness = *def.Hardness
+	}
+
+	return block, errs
+}
+
+// parseHexColor parses #rrggbb or #rrggbbaa
+func parseHexColor(s string) (color.RGBA, error) {
+	hex, ok := strings.CutPrefix(s, "#")
+	if !ok || (len(hex) != 6 && len(hex) != 8) {
+		return color.RGBA{}, fmt.Errorf("color %q is not #rrggbb or #rrggbbaa", s)
+	}
+	if len(hex) == 6 {
+		hex += "ff"
+	}
+
+	v, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return color.RGBA{}, fmt.Errorf("color %q is not #rrggbb or #rrggbbaa", s)
+	}
+	return color.RGBA{R: uint8(v >> 24), G: uint8(v >> 16), B: uint8(v >> 8), A: uint8(v)}, nil
+}
+
+// Get returns the definition of the type
+func (r *BlockRegistry) Get(t VoxelType) (*BlockDefinition, bool) {
+	block, ok := r.blocks[t]
+	return block, ok
+}
+
+// ByName returns the definition with the name
+func (r *BlockRegistry) ByName(name string) (*BlockDefinition, bool) {
+	block, ok := r.byName[name]
+	return block, ok
+}
+
+// Require checks every type is defined, so a world can't place blocks its
+// registry knows nothing about
+func (r *BlockRegistry) Require(types ...VoxelType) error {
+	var errs []error
+	for _, t := range types {
+		if _, ok := r.blocks[t]; !ok {
+			errs = append(errs, fmt.Errorf("block %d isn't defined", t))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Types returns every defined type in ID order, of which there is always at
+// least one
+func (r *BlockRegistry) Types() []VoxelType {
+	types := make([]VoxelType, 0, len(r.blocks))
+	for t := range r.blocks {
+		types = append(types, t)
+	}
+	slices.Sort(types)
+	return types
+}
+
+// Solid reports whether the type collides. Air and unknown types don't
+func (r *BlockRegistry) Solid(t VoxelType) bool {
+	block, ok := r.blocks[t]
+	return ok && block.Solid
+}
+
+// Opaque reports whether the type hides the faces behind it. Air and
+// unknown types don't
+func (r *BlockRegistry) Opaque(t VoxelType) bool {
+	block, ok := r.blocks[t]
+	return ok && !block.Transparent
+}
+
+// Color returns the color of the type, or magenta for unknown types so
+// they stand out
+func (r *BlockRegistry) Color(t VoxelType) color.RGBA {
+	if block, ok := r.blocks[t]; ok {
+		return block.Color
+	}
+	return color.RGBA{R: 255, G: 0, B: 255, A: 255}
+}
+
+// Name returns the name of the type, or its ID for unknown types
+func (r *BlockRegistry) Name(t VoxelType) string {
+	if block, ok := r.blocks[t]; ok {
+		return block.Name
+	}
+	return fmt.Sprintf("block_%d", t)
+}
+
+// NearestType maps a color onto the type with the closest color
+func (r *BlockRegistry) NearestType(c color.RGBA) VoxelType {
+	best, bestDistance := air, -1
+	for _, t := range r.Types() {
+		tc := r.blocks[t].Color
+		dr := int(c.R) - int(tc.R)
+		dg := int(c.G) - int(tc.G)
+		db := int(c.B) - int(tc.B)
+
+		if distance := dr*dr + dg*dg + db*db; bestDistance < 0 || distance < bestDistance {
+			best, bestDistance = t, distance
+		}
+	}
+	return best
+}
@@ -0,0 +1,159 @@
+package game
+
+import (
+	"image/color"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestDefaultBlockRegistry(t *testing.T) {
+	blocks := DefaultBlockRegistry()
+
+	if got, want := blocks.Types(), []VoxelType{grass, dirt, stone}; !slices.Equal(got, want) {
+		t.Errorf("Types() = %v, want %v", got, want)
+	}
+	for _, name := range []string{"grass", "dirt", "stone"} {
+		block, ok := blocks.ByName(name)
+		if !ok || blocks.Name(block.ID) != name || !blocks.Solid(block.ID) || !blocks.Opaque(block.ID) {
+			t.Errorf("%s isn't a solid, opaque block", name)
+		}
+	}
+	if blocks.Solid(air) || blocks.Opaque(air) {
+		t.Error("air is solid or opaque")
+	}
+	if err := blocks.Require((GeneratorSettings{Name: "default"}).Types()...); err != nil {
+		t.Errorf("default generator's blocks aren't defined: %v", err)
+	}
+}
+
+func TestLoadBlockRegistry(t *testing.T) {
+	blocks, err := LoadBlockRegistry(strings.NewReader(`[
+		{"name": "glass", "id": 7, "color": "#ffffff40", "solid": true, "transparent": true},
+		{"name": "lamp", "id": 300, "texture": "lamp.png", "textures": {"top": "lamp_top.png"}, "solid": false, "light_emission": 15, "hardness": 0.3}
+	]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := blocks.Types(); !slices.Equal(got, []VoxelType{7, 300}) {
+		t.Errorf("Types() = %v, want [7 300]", got)
+	}
+
+	glass, _ := blocks.Get(7)
+	if glass.Color != (color.RGBA{255, 255, 255, 0x40}) || !glass.Solid || blocks.Opaque(7) {
+		t.Errorf("glass = %+v", glass)
+	}
+
+	lamp, _ := blocks.ByName("lamp")
+	want := BlockTextures{Top: "lamp_top.png", Side: "lamp.png", Bottom: "lamp.png"}
+	if lamp.Textures != want || lamp.Solid || lamp.LightEmission != 15 || lamp.Hardness != 0.3 {
+		t.Errorf("lamp = %+v", lamp)
+	}
+
+	// Unknown types still have a name and color to show
+	if blocks.Name(8) != "block_8" || blocks.Color(8) != (color.RGBA{255, 0, 255, 255}) {
+		t.Errorf("unknown type has name %q and color %v", blocks.Name(8), blocks.Color(8))
+	}
+}
+
+func TestLoadBlockRegistryErrors(t *testing.T) {
+	tests := map[string]struct {
+		json string
+		errs []string
+	}{
+		"empty":     {`[]`, []string{"no block definitions"}},
+		"null":      {`null`, []string{"no block definitions"}},
+		"not json":  {`{`, []string{"parsing block definitions"}},
+		"not array": {`{"name": "x"}`, []string{"parsing block definitions"}},
+		"unknown field": {
+			`[{"name": "a", "id": 1, "color": "#000000", "solid": true, "colour": "#fff"}]`,
+			[]string{"unknown field"},
+		},
+		"missing fields": {
+			`[{}]`,
+			[]string{"block 0: missing name", "block 0: missing id", "block 0: missing color or texture", "block 0: missing solid"},
+		},
+		"empty name": {
+			`[{"name": "", "id": 1, "color": "#000000", "solid": true}]`,
+			[]string{"block 0: missing name"},
+		},
+		"face without texture": {
+			`[{"name": "a", "id": 1, "textures": {"top": "a.png", "side": "a.png"}, "solid": true}]`,
+			[]string{"block 0: missing color or texture"},
+		},
+		"duplicate id": {
+			`[
+				{"name": "a", "id": 4, "color": "#000000", "solid": true},
+				{"name": "b", "id": 5, "color": "#000000", "solid": true},
+				{"name": "c", "id": 4, "color": "#000000", "solid": true}
+			]`,
+			[]string{`block 2: id 4 is already used by "a"`},
+		},
+		"duplicate name": {
+			`[
+				{"name": "a", "id": 4, "color": "#000000", "solid": true},
+				{"name": "a", "id": 5, "color": "#000000", "solid": true}
+			]`,
+			[]string{`block 1: name "a" is already used`},
+		},
+		"bad values": {
+			`[
+				{"name": "a", "id": 0, "color": "#00000", "solid": true},
+				{"name": "b", "id": 65536, "color": "#gggggg", "solid": true, "light_emission": 16, "hardness": -1}
+			]`,
+			[]string{
+				"block 0: id 0 is reserved for air",
+				`block 0: color "#00000" is not`,
+				"block 1: id 65536 is out of range",
+				`block 1: color "#gggggg" is not`,
+				"block 1: light_emission 16 is out of range",
+				"block 1: hardness -1 is negative",
+			},
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			blocks, err := LoadBlockRegistry(strings.NewReader(test.json))
+			if err == nil {
+				t.Fatalf("loaded %v", blocks.Types())
+			}
+			for _, want := range test.errs {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("error %q doesn't mention %q", err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestBlockRegistryRequire(t *testing.T) {
+	blocks := DefaultBlockRegistry()
+
+	if err := blocks.Require(); err != nil {
+		t.Errorf("Require() = %v", err)
+	}
+	if err := blocks.Require((GeneratorSettings{Name: "flat", Params: map[string]float64{"type": 3}}).Types()...); err != nil {
+		t.Errorf("Require of a flat stone generator = %v", err)
+	}
+
+	err := blocks.Require((GeneratorSettings{Name: "flat", Params: map[string]float64{"type": 9}}).Types()...)
+	if err == nil || !strings.Contains(err.Error(), "block 9 isn't defined") {
+		t.Errorf("Require of a flat generator of undefined blocks = %v", err)
+	}
+	err = blocks.Require(air, stone, 12)
+	if err == nil || !strings.Contains(err.Error(), "block 0") || !strings.Contains(err.Error(), "block 12") {
+		t.Errorf("Require(air, stone, 12) = %v, want air and 12 reported", err)
+	}
+}
+
+func TestNearestType(t *testing.T) {
+	blocks := DefaultBlockRegistry()
+	for _, want := range blocks.Types() {
+		c := blocks.Color(want)
+		c.R++
+		if got := blocks.NearestType(c); got != want {
+			t.Errorf("NearestType(%v) = %v, want %v", c, got, want)
+		}
+	}
+}
@@ -8,7 +8,12 @@ import (
 
 const ninetyDegRadians = 90.0 * (math.Pi / 180.0)
 
-const frustumRenderDistance = 50
+// cameraStartHeight puts the spawn point of new worlds above the terrain
+const cameraStartHeight = 96
+
+// frustumRenderDistance is how far a new camera sees, enough for every
+// column a World streams in at its default ViewRadius
+const frustumRenderDistance = (defaultViewRadius + 2) * float32(chunkLength)
 const frustumNearDistance = 0.1
 
 // Camera represents the player's view
@@ -16,23 +21,24 @@ type Camera struct {
 	Camera3D rl.Camera3D
 
 	Frustum Frustum // currently in world space
+
+	// RenderDistance is how far away the far plane of the frustum is
+	RenderDistance float32
 }
 
-// Create a new camera
-func NewCamera() *Camera {
+// Create a new camera at the position looking at the target
+func NewCamera(position, target rl.Vector3) *Camera {
 	camera := Camera{
 		Camera3D: rl.Camera3D{
-			// Position: rl.NewVector3(0, 10, 0),
-			// Target:   rl.NewVector3(0, 0, 10),
-
-			Position: rl.NewVector3(0, float32(chunkHeight/2)+10, 0),
-			Target:   rl.NewVector3(0, float32(chunkHeight/2), 10),
+			Position: position,
+			Target:   target,
 
 			Up:         rl.NewVector3(0, 1, 0),
 			Fovy:       45.0,
 			Projection: rl.CameraPerspective,
 		},
-		Frustum: Frustum{},
+		Frustum:        Frustum{},
+		RenderDistance: frustumRenderDistance,
 	}
 
 	camera.UpdateFrustum()
@@ -51,7 +57,7 @@ func (c *Camera) UpdateFrustum() {
 	nearPt := rl.Vector3Add(c.Camera3D.Position,
 		rl.Vector3Scale(forward, frustumNearDistance))
 	farPt := rl.Vector3Add(c.Camera3D.Position,
-		rl.Vector3Scale(forward, frustumRenderDistance))
+		rl.Vector3Scale(forward, c.RenderDistance))
 
 	halfFovyRadians := c.Camera3D.Fovy * (math.Pi / 180.0) / 2.0
 
@@ -0,0 +1,82 @@
+package game
+
+import (
+	"math"
+)
+
+// SurfaceHeight reports the world y of the terrain surface for a column
+type SurfaceHeight interface {
+	Height(x, z int) int
+}
+
+// CaveCarver is a generation stage that carves air out of solid voxels using
+// 3D noise. Spaghetti caves are long winding tunnels where two noise fields
+// both cross zero, and cheese caves are large caverns where a third noise
+// field peaks
+type CaveCarver struct {
+	Surface SurfaceHeight
+
+	// Caves are never carved closer than this to the surface
+	MinDepth int
+
+	Spaghetti       [2]NoiseSource
+	SpaghettiRadius float64
+
+	Cheese          NoiseSource
+	CheeseThreshold float64
+}
+
+// Create a new cave carver for the seed. Each noise field is seeded
+// differently so they don't line up with each other or the terrain
+func NewCaveCarver(seed uint64, surface SurfaceHeight) *CaveCarver {
+	tunnel := DefaultFractal(2)
+	tunnel.Frequency = 1.0 / 32
+
+	cavern := DefaultFractal(3)
+	cavern.Frequency = 1.0 / 48
+
+	return &CaveCarver{
+		Surface:  surface,
+		MinDepth: 4,
+		Spaghetti: [2]NoiseSource{
+			FBM{Source: NewPerlin(seed + 1), Fractal: tunnel},
+			FBM{Source: NewPerlin(seed + 2), Fractal: tunnel},
+		},
+		SpaghettiRadius: 0.06,
+		Cheese:          FBM{Source: NewPerlin(seed + 3), Fractal: cavern},
+		CheeseThreshold: 0.4,
+	}
+}
+
+func (cc *CaveCarver) Generate(c *Chunk) {
+	for x := range int(chunkLength) {
+		for z := range int(chunkLength) {
+			column := c.Coord.Block(x, 0, z)
+			maxY := cc.Surface.Height(column.X, column.Z) - cc.MinDepth
+
+			for y := range int(chunkHeight) {
+				pos := c.Coord.Block(x, y, z)
+				if pos.Y > maxY {
+					break
+				}
+				if c.voxelType(x, y, z) == air {
+					continue
+				}
+
+				if cc.carved(float64(pos.X), float64(pos.Y), float64(pos.Z)) {
+					c.setVoxel(x, y, z, air)
+				}
+			}
+		}
+	}
+}
+
+// carved reports whether the voxel at the world position is inside a cave
+func (cc *CaveCarver) carved(x, y, z float64) bool {
+	if cc.Cheese.Noise(x, y, z) > cc.CheeseThreshold {
+		return true
+	}
+
+	return math.Abs(cc.Spaghetti[0].Noise(x, y, z)) < cc.SpaghettiRadius &&
+		math.Abs(cc.Spaghetti[1].Noise(x, y, z)) < cc.SpaghettiRadius
+}
@@ -0,0 +1,115 @@
+package game
+
+import (
+	"testing"
+)
+
+// flatSurface is a surface at the same height everywhere
+type flatSurface int
+
+func (s flatSurface) Height(x, z int) int {
+	return int(s)
+}
+
+// carvedChunk is a chunk of solid stone run through the carver
+func carvedChunk(cc *CaveCarver, coord ChunkCoord) *Chunk {
+	chunk := NewChunk(coord)
+	FlatGenerator{Height: int(chunkHeight), Type: stone}.Generate(&chunk)
+	cc.Generate(&chunk)
+	return &chunk
+}
+
+func TestCaveCarverDeterministic(t *testing.T) {
+	surface := flatSurface(chunkHeight)
+	coord := ChunkCoord{X: -1, Z: 2}
+	a := carvedChunk(NewCaveCarver(5, surface), coord)
+	b := carvedChunk(NewCaveCarver(5, surface), coord)
+	other := carvedChunk(NewCaveCarver(6, surface), coord)
+
+	var carved, differs int
+	for x := range int(chunkLength) {
+		for y := range int(chunkHeight) {
+			for z := range int(chunkLength) {
+				if a.voxelType(x, y, z) != b.voxelType(x, y, z) {
+					t.Fatalf("same seed carved %d, %d, %d differently", x, y, z)
+				}
+				if a.voxelType(x, y, z) == air {
+					carved++
+				}
+				if a.voxelType(x, y, z) != other.voxelType(x, y, z) {
+					differs++
+				}
+			}
+		}
+	}
+	if carved == 0 {
+		t.Error("no caves were carved")
+	}
+	if differs == 0 {
+		t.Error("a different seed carved the same caves")
+	}
+}
+
+func TestCaveCarverMinDepth(t *testing.T) {
+	// Carve everywhere it's allowed to
+	cc := NewCaveCarver(1, flatSurface(12))
+	cc.CheeseThreshold = -2
+
+	chunk := carvedChunk(cc, ChunkCoord{})
+	for x := range int(chunkLength) {
+		for z := range int(chunkLength) {
+			for y := range int(chunkHeight) {
+				want := stone
+				if y <= 12-cc.MinDepth {
+					want = air
+				}
+				if got := chunk.voxelType(x, y, z); got != want {
+					t.Fatalf("voxel %d below the surface is %v, want %v", 12-y, got, want)
+				}
+			}
+		}
+	}
+}
+
+func TestCaveCarverAcrossChunks(t *testing.T) {
+	cc := NewCaveCarver(31, flatSurface(chunkHeight))
+
+	// Carving only depends on the world position, so the chunks on either
+	// side of a border agree with each other
+	chunks := map[ChunkCoord]*Chunk{}
+	for _, coord := range []ChunkCoord{{}, {X: 1}, {Z: 1}} {
+		chunk := carvedChunk(cc, coord)
+		chunks[coord] = chunk
+
+		for x := range int(chunkLength) {
+			for y := range int(chunkHeight) {
+				for z := range int(chunkLength) {
+					pos := coord.Block(x, y, z)
+					want := cc.carved(float64(pos.X), float64(pos.Y), float64(pos.Z)) && y <= int(chunkHeight)-cc.MinDepth
+					if got := chunk.voxelType(x, y, z) == air; got != want {
+						t.Fatalf("voxel at %v carved = %v, want %v", pos, got, want)
+					}
+				}
+			}
+		}
+	}
+
+	// And some cave runs through each border
+	for _, border := range []struct{ a, b ChunkCoord }{{ChunkCoord{}, ChunkCoord{X: 1}}, {ChunkCoord{}, ChunkCoord{Z: 1}}} {
+		var open int
+		for i := range int(chunkLength) {
+			for y := range int(chunkHeight) {
+				a, b := chunks[border.a].voxelType(15, y, i), chunks[border.b].voxelType(0, y, i)
+				if border.a.Z != border.b.Z {
+					a, b = chunks[border.a].voxelType(i, y, 15), chunks[border.b].voxelType(i, y, 0)
+				}
+				if a == air && b == air {
+					open++
+				}
+			}
+		}
+		if open == 0 {
+			t.Errorf("no cave crosses the border between %v and %v", border.a, border.b)
+		}
+	}
+}
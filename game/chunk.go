@@ -1,49 +1,61 @@
 package game
 
 import (
-	"fmt"
+	_ "embed"
 	"image/color"
-	"math/rand/v2"
 
 	rl "github.com/gen2brain/raylib-go/raylib"
 )
 
 const (
 	chunkLength uint8 = 16 // x and z coords
-	chunkHeight uint8 = 16 // y coords
+	chunkHeight uint8 = 16 // y coords of a single section
+
+	chunkVolume = int(chunkLength) * int(chunkHeight) * int(chunkLength)
 )
 
 var (
 	chunkBoundingBoxColor = color.RGBA{255, 0, 0, 255}
 )
 
-type ChunkID string
-
-func (id ChunkID) String() string {
-	return string(id)
-}
+// The chunk shader repeats block textures across greedy meshed quads
+var (
+	//go:embed shaders/chunk.vs
+	chunkVertexShader string
+	//go:embed shaders/chunk.fs
+	chunkFragmentShader string
+)
 
-// Chunk represents a 16x16x16 section of voxels
+// Chunk represents a 16x16x16 section of voxels. Sections are stacked
+// vertically to make up a column of the world
 type Chunk struct {
-	Voxels [chunkLength][chunkHeight][chunkLength]*Voxel
+	// voxels is indexed by voxelIndex
+	voxels paletteStorage
 
-	ID            ChunkID
+	Coord         ChunkCoord
 	worldPosition rl.Vector3
 	boundingBox   rl.BoundingBox
 
-	debugColor color.RGBA
+	// modified is set when the chunk has been edited since it was last saved
+	modified bool
+
+	// mesh is cached and only rebuilt by the workers when meshDirty is set,
+	// then uploaded to gpuMesh on the main thread
+	mesh         ChunkMesh
+	meshDirty    bool
+	meshUploaded bool
+	gpuMesh      rl.Mesh
 
 	// offsets so worldPosition is the center of a chunk when rendered
 	xRenderOffset, zRenderOffset uint8
 }
 
-func NewChunk(xPos, zPos int) Chunk {
+func NewChunk(coord ChunkCoord) Chunk {
+	origin := coord.Origin()
 	chunk := Chunk{
-		worldPosition: rl.NewVector3(float32(xPos), 0, float32(zPos)),
-		ID: ChunkID(fmt.Sprintf("%d,%d",
-			xPos,
-			zPos,
-		)),
+		Coord:         coord,
+		worldPosition: rl.NewVector3(float32(origin.X), float32(origin.Y), float32(origin.Z)),
+		meshDirty:     true,
 	}
 
 	offset := uint8(chunkLength / 2)
@@ -51,84 +63,132 @@ func NewChunk(xPos, zPos int) Chunk {
 	chunk.xRenderOffset = uint8(offset)
 	chunk.zRenderOffset = uint8(offset)
 
-	// Generate the Voxels for the chunk
-	for x := range chunkLength {
-		for y := range uint8(chunkHeight/2 + 1) {
-			for z := range chunkLength {
-				height := Noise(x, y, z)
-				height = 0
-
-				chunk.Voxels[x][uint8(height)][z] = &Voxel{
-					// NOTE: chunk is only single layer deep right now
-					// Position: rl.NewVector3(float32(x), float32(y), float32(z)),
-					Position: rl.NewVector3(
-						float32(x),
-						height,
-						float32(z),
-					),
-					Type: 1,
-				}
-			}
-		}
-	}
-
 	// Chunks don't move, so we only need to calculate the boundingBox once
 	chunk.boundingBox = rl.BoundingBox{
 		Min: rl.Vector3{
 			X: chunk.worldPosition.X - 0.5,
-			Y: 0 - 0.5,
+			Y: chunk.worldPosition.Y - 0.5,
 			Z: chunk.worldPosition.Z - 0.5,
 		},
 		Max: rl.Vector3{
 			X: chunk.worldPosition.X + float32(chunkLength) - 0.5,
-			Y: 255,
+			Y: chunk.worldPosition.Y + float32(chunkHeight) - 0.5,
 			Z: chunk.worldPosition.Z + float32(chunkLength) - 0.5,
 		},
 	}
 	// gonna use rl.DrawCubeWires to draw the bounding box
 
-	// Basically the color that'll render for voxels in the chunk
-	chunk.debugColor = rl.NewColor(
-		rand.N(uint8(255)),
-		rand.N(uint8(255)),
-		rand.N(uint8(255)),
-		255)
-
 	return chunk
 }
 
-// Render a single chunk
-func (c *Chunk) render() {
+// voxelType returns the type of the voxel at the chunk local position,
+// treating anything outside the chunk as air
+func (c *Chunk) voxelType(x, y, z int) VoxelType {
+	if x < 0 || y < 0 || z < 0 ||
+		x >= int(chunkLength) || y >= int(chunkHeight) || z >= int(chunkLength) {
+		return air
+	}
+
+	return c.voxels.get(voxelIndex(x, y, z))
+}
+
+// setVoxel sets the voxel at the chunk local position, where air removes it
+func (c *Chunk) setVoxel(x, y, z int, t VoxelType) {
+	c.voxels.set(voxelIndex(x, y, z), t)
+	c.meshDirty = true
+}
+
+// voxelIndex is the index of the chunk local position in the voxel storage.
+// Each horizontal layer is stored contiguously, one after the other going up
+func voxelIndex(x, y, z int) int {
+	return x + z*int(chunkLength) + y*int(chunkLength)*int(chunkLength)
+}
+
+// remesh rebuilds the chunk mesh, textured from the atlas. It doesn't touch
+// the GPU so it's safe to call off the main thread
+func (c *Chunk) remesh(lookup VoxelLookup, blocks *BlockRegistry, atlas *BlockAtlas) {
+	c.setMesh(BuildGreedyMesh(lookup, blocks, atlas))
+	c.meshDirty = false
+}
+
+// setMesh replaces the chunk mesh, which is uploaded the next time the chunk
+// is rendered
+func (c *Chunk) setMesh(mesh ChunkMesh) {
+	c.mesh = mesh
+	c.meshUploaded = false
+}
+
+// upload replaces the chunk's GPU mesh with the current mesh
+func (c *Chunk) upload() {
+	if c.gpuMesh.VaoID != 0 {
+		rl.UnloadMesh(&c.gpuMesh)
+	}
+
+	c.gpuMesh = c.mesh.toRaylib()
+	if c.gpuMesh.TriangleCount > 0 {
+		rl.UploadMesh(&c.gpuMesh, false)
+	}
+
+	c.meshUploaded = true
+}
+
+// unload frees the chunk's GPU resources
+func (c *Chunk) unload() {
+	if c.gpuMesh.VaoID != 0 {
+		rl.UnloadMesh(&c.gpuMesh)
+	}
+}
+
+// loadChunkMaterial creates the material every chunk is drawn with, using
+// the chunk shader to texture faces from the atlas
+func loadChunkMaterial(atlas rl.Texture2D) rl.Material {
+	material := rl.LoadMaterialDefault()
+	material.Shader = rl.LoadShaderFromMemory(chunkVertexShader, chunkFragmentShader)
+	material.Maps.Texture = atlas
+	return material
+}
+
+// unloadChunkMaterial frees the chunk material and its shader. The atlas is
+// swapped back out for raylib's default texture first, as unloading a
+// material frees its textures and the atlas is owned by the engine
+func unloadChunkMaterial(material rl.Material) {
+	material.Maps.Texture = rl.Texture2D{
+		ID:      rl.GetTextureIdDefault(),
+		Width:   1,
+		Height:  1,
+		Mipmaps: 1,
+		Format:  rl.UncompressedR8g8b8a8,
+	}
+	rl.UnloadMaterial(material)
+}
+
+// Render a single chunk with the chunk material, uploading its mesh first if
+// it has been rebuilt
+func (c *Chunk) render(material rl.Material) {
 
 	// Eventually add a check for whether the chunk is in view of the frustum
 	rl.DrawBoundingBox(c.boundingBox, chunkBoundingBoxColor)
 
-	for x := range chunkLength {
-		for y := range chunkHeight {
-			for z := range chunkLength {
-
-				// Skip missing voxels
-				if c.Voxels[x][y][z] == nil {
-					continue
-				}
-
-				// Draw voxels in the chunk with the c.worldPosition
-				// being at the center
-				position := rl.Vector3Add(
-					c.worldPosition,
-					rl.NewVector3(
-						float32(x),
-						float32(y),
-						float32(z),
-					),
-				)
-
-				// used for individual random voxel color
-				// color := rl.NewColor(uint8(x*16), uint8(y*16), uint8(z*16), 255)
-
-				rl.DrawCube(position, 1, 1, 1, c.debugColor)
-				rl.DrawCubeWires(position, 1, 1, 1, VoxelOutlineColor)
-			}
-		}
+	if !c.meshUploaded {
+		c.upload()
 	}
+	if c.gpuMesh.TriangleCount == 0 {
+		return
+	}
+
+	// Mesh vertices are at the voxel corners, but voxels are centered on
+	// their position
+	transform := rl.MatrixTranslate(
+		c.worldPosition.X-0.5,
+		c.worldPosition.Y-0.5,
+		c.worldPosition.Z-0.5,
+	)
+
+	material.Maps.Color = rl.White
+	rl.DrawMesh(c.gpuMesh, material, transform)
+
+	material.Maps.Color = VoxelOutlineColor
+	rl.EnableWireMode()
+	rl.DrawMesh(c.gpuMesh, material, transform)
+	rl.DisableWireMode()
 }
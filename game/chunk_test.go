@@ -0,0 +1,146 @@
+package game
+
+import (
+	"runtime"
+	"testing"
+	"time"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// benchmarkWorldRadius is the radius in chunks of the world built by the
+// storage benchmarks. Columns are one section deep, taken where the terrain
+// surface is so chunks hold a mix of air, grass, dirt and stone
+const (
+	benchmarkWorldRadius  = 32
+	benchmarkWorldSection = 3
+)
+
+// legacyVoxel and legacyChunk are the storage chunks used before voxels
+// were kept as flat IDs: a pointer per voxel, each to its own allocation
+// holding a redundant position
+type legacyVoxel struct {
+	Position rl.Vector3
+	Type     int
+}
+
+type legacyChunk struct {
+	Voxels [chunkLength][chunkHeight][chunkLength]*legacyVoxel
+}
+
+func newLegacyChunk(c *Chunk) *legacyChunk {
+	legacy := &legacyChunk{}
+	for x := range int(chunkLength) {
+		for y := range int(chunkHeight) {
+			for z := range int(chunkLength) {
+				if t := c.voxelType(x, y, z); t != air {
+					legacy.Voxels[x][y][z] = &legacyVoxel{
+						Position: rl.NewVector3(float32(x), float32(y), float32(z)),
+						Type:     int(t),
+					}
+				}
+			}
+		}
+	}
+	return legacy
+}
+
+// benchmarkWorldStorage builds a world of benchmarkWorldRadius, keeping
+// whatever store returns for each generated chunk. It reports the heap
+// used per chunk and how long a full garbage collection takes, along with
+// its stop the world pause, while the whole world is live
+func benchmarkWorldStorage(b *testing.B, store func(c *Chunk) any) {
+	generator := NewDefaultGenerator(1)
+
+	for range b.N {
+		var before, after runtime.MemStats
+		runtime.GC()
+		runtime.ReadMemStats(&before)
+
+		var world []any
+		for x := -benchmarkWorldRadius; x <= benchmarkWorldRadius; x++ {
+			for z := -benchmarkWorldRadius; z <= benchmarkWorldRadius; z++ {
+				chunk := NewChunk(ChunkCoord{X: int32(x), Y: benchmarkWorldSection, Z: int32(z)})
+				generator.Generate(&chunk)
+				world = append(world, store(&chunk))
+			}
+		}
+
+		runtime.GC()
+		runtime.ReadMemStats(&after)
+		b.ReportMetric(float64(after.HeapAlloc-before.HeapAlloc)/float64(len(world)), "heap-B/chunk")
+
+		start := time.Now()
+		runtime.GC()
+		b.ReportMetric(float64(time.Since(start).Nanoseconds()), "gc-ns")
+
+		runtime.ReadMemStats(&after)
+		b.ReportMetric(float64(after.PauseNs[(after.NumGC+255)%256]), "gc-pause-ns")
+
+		runtime.KeepAlive(world)
+	}
+}
+
+// Run with -benchtime=1x, each iteration generates over four thousand chunks
+func BenchmarkWorldStorageLegacy(b *testing.B) {
+	benchmarkWorldStorage(b, func(c *Chunk) any { return newLegacyChunk(c) })
+}
+
+// BenchmarkWorldStorageFlat keeps every voxel as a VoxelType, the layout
+// before palette compression
+func BenchmarkWorldStorageFlat(b *testing.B) {
+	benchmarkWorldStorage(b, func(c *Chunk) any {
+		flat := new([chunkVolume]VoxelType)
+		for i := range flat {
+			flat[i] = c.voxels.get(i)
+		}
+		return flat
+	})
+}
+
+func BenchmarkWorldStorage(b *testing.B) {
+	benchmarkWorldStorage(b, func(c *Chunk) any { return &c.voxels })
+}
+
+func TestChunkVoxels(t *testing.T) {
+	c := NewChunk(ChunkCoord{})
+
+	if got := c.voxelType(3, 4, 5); got != air {
+		t.Fatalf("new chunk voxel = %v, want air", got)
+	}
+
+	c.meshDirty = false
+	c.setVoxel(3, 4, 5, stone)
+	c.setVoxel(15, 15, 15, dirt)
+	if !c.meshDirty {
+		t.Error("setVoxel didn't mark the mesh dirty")
+	}
+	if got := c.voxelType(3, 4, 5); got != stone {
+		t.Errorf("voxelType(3, 4, 5) = %v, want stone", got)
+	}
+	if got := c.voxelType(15, 15, 15); got != dirt {
+		t.Errorf("voxelType(15, 15, 15) = %v, want dirt", got)
+	}
+
+	// Outside the chunk is air
+	for _, p := range [][3]int{{-1, 0, 0}, {16, 0, 0}, {0, -1, 0}, {0, 16, 0}, {0, 0, -1}, {0, 0, 16}} {
+		if got := c.voxelType(p[0], p[1], p[2]); got != air {
+			t.Errorf("voxelType(%v) = %v, want air", p, got)
+		}
+	}
+}
+
+func TestVoxelIndexIsUnique(t *testing.T) {
+	seen := make([]bool, chunkVolume)
+	for x := range int(chunkLength) {
+		for y := range int(chunkHeight) {
+			for z := range int(chunkLength) {
+				i := voxelIndex(x, y, z)
+				if i < 0 || i >= chunkVolume || seen[i] {
+					t.Fatalf("voxelIndex(%d, %d, %d) = %d is out of range or reused", x, y, z, i)
+				}
+				seen[i] = true
+			}
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package game
+
+import (
+	"fmt"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// ChunkCoord addresses a chunk section in units of chunks, so the chunk at
+// (1, 0, -1) has its origin voxel at world (16, 0, -16)
+type ChunkCoord struct {
+	X, Y, Z int32
+}
+
+func (c ChunkCoord) String() string {
+	return fmt.Sprintf("%d,%d,%d", c.X, c.Y, c.Z)
+}
+
+// Origin is the world position of the voxel at local (0, 0, 0)
+func (c ChunkCoord) Origin() BlockPos {
+	return BlockPos{
+		X: int(c.X) * int(chunkLength),
+		Y: int(c.Y) * int(chunkHeight),
+		Z: int(c.Z) * int(chunkLength),
+	}
+}
+
+// Block converts a local voxel index in the chunk to a world position
+func (c ChunkCoord) Block(x, y, z int) BlockPos {
+	origin := c.Origin()
+	return BlockPos{X: origin.X + x, Y: origin.Y + y, Z: origin.Z + z}
+}
+
+// Neighbour returns the coord offset from this one by whole chunks
+func (c ChunkCoord) Neighbour(dx, dy, dz int32) ChunkCoord {
+	return ChunkCoord{X: c.X + dx, Y: c.Y + dy, Z: c.Z + dz}
+}
+
+// faceOffsets are the directions of the six face sharing neighbours
+var faceOffsets = [6]ChunkCoord{
+	{X: 1}, {X: -1},
+	{Y: 1}, {Y: -1},
+	{Z: 1}, {Z: -1},
+}
+
+// Neighbours returns the six chunks sharing a face with this one
+func (c ChunkCoord) Neighbours() [6]ChunkCoord {
+	var neighbours [6]ChunkCoord
+	for i, offset := range faceOffsets {
+		neighbours[i] = c.Neighbour(offset.X, offset.Y, offset.Z)
+	}
+	return neighbours
+}
+
+// BlockPos is the integer world position of a voxel
+type BlockPos struct {
+	X, Y, Z int
+}
+
+// Chunk returns the coord of the chunk containing the voxel
+func (p BlockPos) Chunk() ChunkCoord {
+	return ChunkCoord{
+		X: int32(floorDiv(p.X, int(chunkLength))),
+		Y: int32(floorDiv(p.Y, int(chunkHeight))),
+		Z: int32(floorDiv(p.Z, int(chunkLength))),
+	}
+}
+
+// Add offsets the position, such as by a RaycastHit's Normal
+func (p BlockPos) Add(o BlockPos) BlockPos {
+	return BlockPos{X: p.X + o.X, Y: p.Y + o.Y, Z: p.Z + o.Z}
+}
+
+// Vector3 returns the center of the voxel
+func (p BlockPos) Vector3() rl.Vector3 {
+	return rl.NewVector3(float32(p.X), float32(p.Y), float32(p.Z))
+}
+
+// Local returns the voxel index within its chunk
+func (p BlockPos) Local() (x, y, z int) {
+	return floorMod(p.X, int(chunkLength)),
+		floorMod(p.Y, int(chunkHeight)),
+		floorMod(p.Z, int(chunkLength))
+}
+
+// floorDiv divides rounding towards negative infinity, so -1 / 16 is -1
+// rather than 0
+func floorDiv(a, b int) int {
+	q := a / b
+	if (a%b != 0) && ((a < 0) != (b < 0)) {
+		q--
+	}
+	return q
+}
+
+// floorMod is the remainder of floorDiv, which is never negative for
+// positive b
+func floorMod(a, b int) int {
+	m := a % b
+	if m < 0 {
+		m += b
+	}
+	return m
+}
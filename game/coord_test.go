@@ -0,0 +1,129 @@
+package game
+
+import "testing"
+
+func TestFloorDivMod(t *testing.T) {
+	tests := []struct {
+		a, div, mod int
+	}{
+		{0, 0, 0},
+		{1, 0, 1},
+		{15, 0, 15},
+		{16, 1, 0},
+		{17, 1, 1},
+		{31, 1, 15},
+		{32, 2, 0},
+		{-1, -1, 15},
+		{-15, -1, 1},
+		{-16, -1, 0},
+		{-17, -2, 15},
+		{-32, -2, 0},
+		{-33, -3, 15},
+	}
+	for _, test := range tests {
+		if got := floorDiv(test.a, 16); got != test.div {
+			t.Errorf("floorDiv(%d, 16) = %d, want %d", test.a, got, test.div)
+		}
+		if got := floorMod(test.a, 16); got != test.mod {
+			t.Errorf("floorMod(%d, 16) = %d, want %d", test.a, got, test.mod)
+		}
+		if got := floorDiv(test.a, 16)*16 + floorMod(test.a, 16); got != test.a {
+			t.Errorf("floorDiv(%d, 16)*16 + floorMod(%d, 16) = %d", test.a, test.a, got)
+		}
+	}
+}
+
+func TestBlockPosChunkAndLocal(t *testing.T) {
+	tests := []struct {
+		pos     BlockPos
+		chunk   ChunkCoord
+		x, y, z int
+	}{
+		{BlockPos{0, 0, 0}, ChunkCoord{0, 0, 0}, 0, 0, 0},
+		{BlockPos{15, 15, 15}, ChunkCoord{0, 0, 0}, 15, 15, 15},
+		{BlockPos{16, 16, 16}, ChunkCoord{1, 1, 1}, 0, 0, 0},
+		{BlockPos{-1, -1, -1}, ChunkCoord{-1, -1, -1}, 15, 15, 15},
+		{BlockPos{-16, -16, -16}, ChunkCoord{-1, -1, -1}, 0, 0, 0},
+		{BlockPos{-17, 0, 17}, ChunkCoord{-2, 0, 1}, 15, 0, 1},
+		{BlockPos{100, -100, -1000}, ChunkCoord{6, -7, -63}, 4, 12, 8},
+	}
+	for _, test := range tests {
+		if got := test.pos.Chunk(); got != test.chunk {
+			t.Errorf("%v.Chunk() = %v, want %v", test.pos, got, test.chunk)
+		}
+
+		x, y, z := test.pos.Local()
+		if x != test.x || y != test.y || z != test.z {
+			t.Errorf("%v.Local() = (%d, %d, %d), want (%d, %d, %d)", test.pos, x, y, z, test.x, test.y, test.z)
+		}
+
+		// Going back through the chunk gives the same position
+		if got := test.chunk.Block(x, y, z); got != test.pos {
+			t.Errorf("%v.Block(%d, %d, %d) = %v, want %v", test.chunk, x, y, z, got, test.pos)
+		}
+	}
+}
+
+func TestChunkCoordOrigin(t *testing.T) {
+	tests := map[ChunkCoord]BlockPos{
+		{0, 0, 0}:   {0, 0, 0},
+		{1, 0, -1}:  {16, 0, -16},
+		{-2, 3, 4}:  {-32, 48, 64},
+		{-1, -1, 0}: {-16, -16, 0},
+	}
+	for coord, want := range tests {
+		if got := coord.Origin(); got != want {
+			t.Errorf("%v.Origin() = %v, want %v", coord, got, want)
+		}
+		if got := want.Chunk(); got != coord {
+			t.Errorf("%v.Chunk() = %v, want %v", want, got, coord)
+		}
+	}
+}
+
+func TestChunkCoordNeighbours(t *testing.T) {
+	c := ChunkCoord{X: -1, Y: 0, Z: 5}
+
+	if got, want := c.Neighbour(2, -1, -6), (ChunkCoord{X: 1, Y: -1, Z: -1}); got != want {
+		t.Errorf("Neighbour(2, -1, -6) = %v, want %v", got, want)
+	}
+
+	want := [6]ChunkCoord{
+		{X: 0, Y: 0, Z: 5}, {X: -2, Y: 0, Z: 5},
+		{X: -1, Y: 1, Z: 5}, {X: -1, Y: -1, Z: 5},
+		{X: -1, Y: 0, Z: 6}, {X: -1, Y: 0, Z: 4},
+	}
+	if got := c.Neighbours(); got != want {
+		t.Errorf("Neighbours() = %v, want %v", got, want)
+	}
+
+	// Every neighbour's block right next to the shared face is in the
+	// neighbouring chunk
+	origin := c.Origin()
+	edges := [6]BlockPos{
+		{X: origin.X + 16}, {X: origin.X - 1},
+		{Y: origin.Y + 16}, {Y: origin.Y - 1},
+		{Z: origin.Z + 16}, {Z: origin.Z - 1},
+	}
+	for i, edge := range edges {
+		// Fill in the axes that aren't being tested from the origin
+		if i/2 != 0 {
+			edge.X = origin.X
+		}
+		if i/2 != 1 {
+			edge.Y = origin.Y
+		}
+		if i/2 != 2 {
+			edge.Z = origin.Z
+		}
+		if got := edge.Chunk(); got != want[i] {
+			t.Errorf("%v.Chunk() = %v, want %v", edge, got, want[i])
+		}
+	}
+}
+
+func TestChunkCoordString(t *testing.T) {
+	if got := (ChunkCoord{X: -1, Y: 2, Z: -3}).String(); got != "-1,2,-3" {
+		t.Errorf("String() = %q", got)
+	}
+}
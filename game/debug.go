@@ -21,7 +21,7 @@ func NewDebugger(e *Engine) Debugger {
 }
 
 type debugRenderInfo struct {
-	chunksRendered []ChunkID
+	chunksRendered []ChunkCoord
 }
 
 func (d Debugger) Render(info debugRenderInfo) {
@@ -30,6 +30,17 @@ func (d Debugger) Render(info debugRenderInfo) {
 	d.FrustumDebug() // camera frustum
 	d.CameraDebug()
 	d.ChunkDebug(info.chunksRendered)
+	d.BlockDebug()
+}
+
+func (d Debugger) BlockDebug() {
+	rl.DrawText(
+		fmt.Sprintf(
+			"Selected Block: %s",
+			d.engine.World.Blocks.Name(d.engine.Input.Selected),
+		),
+		10, int32(rl.GetScreenHeight())-30, 20, rl.Black,
+	)
 }
 
 func (d Debugger) CameraDebug() {
@@ -53,8 +64,8 @@ func (d Debugger) CameraDebug() {
 	)
 	rl.DrawText(
 		fmt.Sprintf(
-			"Origin Chunk Pos: (%#v)",
-			d.engine.World.Chunks["0,0"].worldPosition,
+			"Camera Chunk: (%s)",
+			blockAt(d.engine.Camera3D.Position).Chunk(),
 		),
 		10, 70, 20, rl.Black,
 	)
@@ -123,10 +134,10 @@ func (d Debugger) FrustumDebug() {
 	)
 }
 
-func (d Debugger) ChunkDebug(ids []ChunkID) {
-	idsStr := make([]string, len(ids))
-	for i, id := range ids {
-		idsStr[i] = string(id)
+func (d Debugger) ChunkDebug(coords []ChunkCoord) {
+	idsStr := make([]string, len(coords))
+	for i, coord := range coords {
+		idsStr[i] = coord.String()
 	}
 	sort.Strings(idsStr)
 
@@ -147,12 +158,12 @@ func (d Debugger) ChunkDebug(ids []ChunkID) {
 	)
 
 	var chunkTxtPos int32 = 160
-	for _, id := range ids {
+	for _, coord := range coords {
 		chunkTxtPos += 20
 		rl.DrawText(
 			fmt.Sprintf(
 				"Chunk Pos: (%#v)",
-				d.engine.World.Chunks[id].worldPosition,
+				d.engine.World.Chunks[coord].worldPosition,
 			),
 			10, chunkTxtPos, 20, rl.Black,
 		)
@@ -1,6 +1,10 @@
 package game
 
 import (
+	"fmt"
+	"os"
+	"path/filepath"
+
 	rl "github.com/gen2brain/raylib-go/raylib"
 )
 
@@ -11,17 +15,73 @@ type Engine struct {
 	World    *World
 	Debugger *Debugger
 	Input    *InputHandler
+
+	// Simulation moves on in fixed ticks, with accumulator holding the
+	// frame time that hasn't been simulated yet
+	Simulation  *Simulation
+	accumulator float32
+
+	// atlasTexture is the World's block atlas on the GPU, which every chunk
+	// is drawn from with chunkMaterial
+	atlasTexture  rl.Texture2D
+	chunkMaterial rl.Material
+
+	// The world is saved to worldDir, described by Metadata
+	worldDir string
+	Metadata *WorldMetadata
 }
 
-// Initialize the engine
-func NewEngine() (*Engine, error) {
+// Initialize the engine with the world in the directory, which is created
+// if it doesn't exist
+func NewEngine(worldDir string) (*Engine, error) {
+	meta, err := OpenWorldMetadata(worldDir)
+	if err != nil {
+		return nil, err
+	}
+
+	generator, err := meta.Generator.NewGenerator(meta.Seed)
+	if err != nil {
+		return nil, err
+	}
+
+	store, err := NewRegionStore(filepath.Join(worldDir, worldRegionDir))
+	if err != nil {
+		return nil, err
+	}
+
+	blocks := DefaultBlockRegistry()
+	blocksPath := filepath.Join(worldDir, worldBlocksFile)
+	if _, err := os.Stat(blocksPath); err == nil {
+		if blocks, err = LoadBlockRegistryFile(blocksPath); err != nil {
+			return nil, err
+		}
+	}
+	if err := blocks.Require(meta.Generator.Types()...); err != nil {
+		return nil, fmt.Errorf("generator %q places undefined blocks: %w", meta.Generator.Name, err)
+	}
+
+	atlas, err := LoadBlockAtlas(blocks, filepath.Join(worldDir, worldTexturesDir))
+	if err != nil {
+		return nil, err
+	}
+
 	rl.InitWindow(1000, 800, "Voxel Engine")
 	rl.SetTargetFPS(60)
 
+	start := meta.StartCamera()
+	world := NewWorld(generator, store, blocks, atlas)
 	engine := &Engine{
-		World:  NewWorld(),
-		Camera: NewCamera(),
+		World:        world,
+		Simulation:   NewSimulation(world, start.Position),
+		atlasTexture: rl.LoadTextureFromImage(rl.NewImageFromImage(atlas.Image)),
+		Camera:       NewCamera(start.Position, start.Target),
+		worldDir:     worldDir,
+		Metadata:     meta,
 	}
+	engine.chunkMaterial = loadChunkMaterial(engine.atlasTexture)
+
+	// See every column that's streamed in
+	engine.Camera.RenderDistance = engine.World.RenderDistance()
 
 	// Initialize the debugger
 	debugger := NewDebugger(engine)
@@ -49,14 +109,46 @@ func GetHorizontalAngleToForward(cameraVec rl.Vector3) float32 {
 	return angle
 }
 
-// Main render loop
-func (e *Engine) Run() {
+// Main render loop, which saves the world when the window is closed
+func (e *Engine) Run() error {
+	defer rl.CloseWindow()
+	defer rl.UnloadTexture(e.atlasTexture)
+	defer unloadChunkMaterial(e.chunkMaterial)
+
 	for !rl.WindowShouldClose() {
 		e.Input.Handle()
+
+		// Run as many ticks as fit in the time since the last frame. Long
+		// frames are cut short so a stall doesn't need endless catching up
+		e.accumulator += min(rl.GetFrameTime(), maxFrameTime)
+		for e.accumulator >= tickDuration {
+			e.Simulation.Tick(e.Input.TickInput())
+			e.accumulator -= tickDuration
+		}
+
+		// Render between the last two ticks, keeping the direction the
+		// mouse turned the camera to this frame
+		look := rl.Vector3Subtract(e.Camera3D.Target, e.Camera3D.Position)
+		e.Camera3D.Position = e.Simulation.InterpolatedEye(e.accumulator / tickDuration)
+		e.Camera3D.Target = rl.Vector3Add(e.Camera3D.Position, look)
+
 		e.Camera.UpdateFrustum()
+		if err := e.World.Stream(e.Camera); err != nil {
+			return err
+		}
+		e.World.UpdateMeshes()
 		e.render()
 	}
-	rl.CloseWindow()
+
+	if err := e.World.Close(); err != nil {
+		return err
+	}
+
+	e.Metadata.Camera = &CameraState{
+		Position: e.Camera3D.Position,
+		Target:   e.Camera3D.Target,
+	}
+	return e.Metadata.Save(e.worldDir)
 }
 
 // Render the world
@@ -65,7 +157,7 @@ func (e *Engine) render() {
 	rl.ClearBackground(rl.RayWhite)
 	rl.BeginMode3D(e.Camera3D)
 
-	chunksRendered := []ChunkID{}
+	chunksRendered := []ChunkCoord{}
 
 	// Render chunks
 	for _, chunk := range e.World.Chunks {
@@ -73,11 +165,16 @@ func (e *Engine) render() {
 		// might pass this info to the chunk if only part of the chunk
 		// is interecting so only some voxels are rendered
 		if e.Camera.Frustum.Viewable(chunk.boundingBox) {
-			chunksRendered = append(chunksRendered, chunk.ID)
-			chunk.render()
+			chunksRendered = append(chunksRendered, chunk.Coord)
+			chunk.render(e.chunkMaterial)
 		}
 	}
 
+	// Outline the block that will be broken or placed against
+	if e.Simulation.Targeting {
+		rl.DrawCubeWiresV(e.Simulation.Target.Block.Vector3(), rl.NewVector3(1.02, 1.02, 1.02), rl.White)
+	}
+
 	rl.EndMode3D()
 
 	e.Debugger.Render(debugRenderInfo{
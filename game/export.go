@@ -0,0 +1,386 @@
+package game
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"strings"
+)
+
+// typeGeometry is the world space geometry of every quad of one VoxelType
+type typeGeometry struct {
+	Type      VoxelType
+	Positions []float32 // xyz per vertex
+	Normals   []float32 // xyz per vertex
+	Indices   []uint32  // two triangles per quad
+}
+
+// boxCorners returns the minimum and maximum corners of the box between the
+// world positions
+func boxCorners(from, to BlockPos) (lo, hi BlockPos) {
+	lo = BlockPos{X: min(from.X, to.X), Y: min(from.Y, to.Y), Z: min(from.Z, to.Z)}
+	hi = BlockPos{X: max(from.X, to.X), Y: max(from.Y, to.Y), Z: max(from.Z, to.Z)}
+	return lo, hi
+}
+
+// ChunksInBox returns the chunks overlapping the box between the world
+// positions, inclusive, whether or not they're loaded
+func (w *World) ChunksInBox(from, to BlockPos) []ChunkCoord {
+	lo, hi := boxCorners(from, to)
+	loChunk, hiChunk := lo.Chunk(), hi.Chunk()
+
+	var coords []ChunkCoord
+	for x := loChunk.X; x <= hiChunk.X; x++ {
+		for y := loChunk.Y; y <= hiChunk.Y; y++ {
+			for z := loChunk.Z; z <= hiChunk.Z; z++ {
+				coords = append(coords, ChunkCoord{X: x, Y: y, Z: z})
+			}
+		}
+	}
+	return coords
+}
+
+// exportGeometry meshes the voxels in the box between the world positions
+// and groups the quads by VoxelType in world space, with voxels centered on
+// their position like when rendered. Voxels outside the box count as air,
+// so the geometry is clipped to the box and closed off where the box cuts
+// through solid blocks. Chunks that aren't loaded are exported as saved or
+// generated
+func (w *World) exportGeometry(from, to BlockPos, greedy bool) []*typeGeometry {
+	lo, hi := boxCorners(from, to)
+	voxels := w.exportVoxels()
+	byType := make(map[VoxelType]*typeGeometry)
+
+	for _, coord := range w.ChunksInBox(from, to) {
+		origin := coord.Origin()
+		lookup := func(x, y, z int) VoxelType {
+			pos := BlockPos{X: origin.X + x, Y: origin.Y + y, Z: origin.Z + z}
+			if pos.X < lo.X || pos.Y < lo.Y || pos.Z < lo.Z || pos.X > hi.X || pos.Y > hi.Y || pos.Z > hi.Z {
+				return air
+			}
+			return voxels(pos)
+		}
+
+		var mesh ChunkMesh
+		if greedy {
+			mesh = BuildGreedyMesh(lookup, w.Blocks, nil)
+		} else {
+			mesh = BuildCulledMesh(lookup, w.Blocks, nil)
+		}
+
+		offset := [3]float32{
+			float32(origin.X) - 0.5,
+			float32(origin.Y) - 0.5,
+			float32(origin.Z) - 0.5,
+		}
+
+		for quad, t := range mesh.Types {
+			geometry, ok := byType[t]
+			if !ok {
+				geometry = &typeGeometry{Type: t}
+				byType[t] = geometry
+			}
+
+			base := uint32(len(geometry.Positions) / 3)
+			for v := quad * 4; v < quad*4+4; v++ {
+				for axis := range 3 {
+					geometry.Positions = append(geometry.Positions, mesh.Vertices[v*3+axis]+offset[axis])
+					geometry.Normals = append(geometry.Normals, mesh.Normals[v*3+axis])
+				}
+			}
+			for _, index := range mesh.Indices[quad*6 : quad*6+6] {
+				geometry.Indices = append(geometry.Indices, base+uint32(index)-uint32(quad*4))
+			}
+		}
+	}
+
+	geometries := make([]*typeGeometry, 0, len(byType))
+	for _, geometry := range byType {
+		geometries = append(geometries, geometry)
+	}
+	slices.SortFunc(geometries, func(a, b *typeGeometry) int {
+		return int(a.Type) - int(b.Type)
+	})
+	return geometries
+}
+
+// objName makes a name safe to write in an OBJ or MTL statement, which ends
+// at whitespace or a newline, by replacing anything but letters, digits, '_',
+// '-' and '.' with '_'
+func objName(name string) string {
+	if name == "" {
+		return "_"
+	}
+
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9',
+			r == '_', r == '-', r == '.':
+			return r
+		default:
+			return '_'
+		}
+	}, name)
+}
+
+// ExportOBJ writes the meshed voxels in the box between the world positions,
+// inclusive, as Wavefront OBJ, with one material per VoxelType written to
+// mtl. mtlName is the file name the OBJ refers to the materials by. greedy
+// merges coplanar faces into larger quads
+func (w *World) ExportOBJ(obj, mtl io.Writer, mtlName string, from, to BlockPos, greedy bool) error {
+	geometries := w.exportGeometry(from, to, greedy)
+
+	o := bufio.NewWriter(obj)
+	fmt.Fprintf(o, "mtllib %s\n", mtlName)
+
+	// OBJ indices are 1 based and shared across the whole file
+	var vertexCount uint32
+	for _, geometry := range geometries {
+		name := objName(w.Blocks.Name(geometry.Type))
+		fmt.Fprintf(o, "o %s\nusemtl %s\n", name, name)
+
+		for v := 0; v < len(geometry.Positions); v += 3 {
+			fmt.Fprintf(o, "v %g %g %g\n", geometry.Positions[v], geometry.Positions[v+1], geometry.Positions[v+2])
+		}
+		for v := 0; v < len(geometry.Normals); v += 3 {
+			fmt.Fprintf(o, "vn %g %g %g\n", geometry.Normals[v], geometry.Normals[v+1], geometry.Normals[v+2])
+		}
+		for i := 0; i < len(geometry.Indices); i += 3 {
+			a, b, c := geometry.Indices[i]+vertexCount+1, geometry.Indices[i+1]+vertexCount+1, geometry.Indices[i+2]+vertexCount+1
+			fmt.Fprintf(o, "f %d//%d %d//%d %d//%d\n", a, a, b, b, c, c)
+		}
+
+		vertexCount += uint32(len(geometry.Positions) / 3)
+	}
+	if err := o.Flush(); err != nil {
+		return fmt.Errorf("writing obj: %w", err)
+	}
+
+	m := bufio.NewWriter(mtl)
+	for _, geometry := range geometries {
+		c := w.Blocks.Color(geometry.Type)
+		fmt.Fprintf(m, "newmtl %s\nKd %.4f %.4f %.4f\nd %.4f\n\n",
+			objName(w.Blocks.Name(geometry.Type)),
+			float64(c.R)/255, float64(c.G)/255, float64(c.B)/255, float64(c.A)/255,
+		)
+	}
+	if err := m.Flush(); err != nil {
+		return fmt.Errorf("writing mtl: %w", err)
+	}
+
+	return nil
+}
+
+// glTF 2.0 document, only the parts needed for static meshes
+type gltfDocument struct {
+	Asset       gltfAsset        `json:"asset"`
+	Scene       int              `json:"scene"`
+	Scenes      []gltfScene      `json:"scenes"`
+	Nodes       []gltfNode       `json:"nodes,omitempty"`
+	Meshes      []gltfMesh       `json:"meshes,omitempty"`
+	Materials   []gltfMaterial   `json:"materials,omitempty"`
+	Buffers     []gltfBuffer     `json:"buffers,omitempty"`
+	BufferViews []gltfBufferView `json:"bufferViews,omitempty"`
+	Accessors   []gltfAccessor   `json:"accessors,omitempty"`
+}
+
+type gltfAsset struct {
+	Version   string `json:"version"`
+	Generator string `json:"generator"`
+}
+
+type gltfScene struct {
+	Nodes []int `json:"nodes,omitempty"`
+}
+
+type gltfNode struct {
+	Mesh int `json:"mesh"`
+}
+
+type gltfMesh struct {
+	Primitives []gltfPrimitive `json:"primitives"`
+}
+
+type gltfPrimitive struct {
+	Attributes map[string]int `json:"attributes"`
+	Indices    int            `json:"indices"`
+	Material   int            `json:"material"`
+}
+
+type gltfMaterial struct {
+	Name string  `json:"name"`
+	PBR  gltfPBR `json:"pbrMetallicRoughness"`
+}
+
+type gltfPBR struct {
+	BaseColorFactor [4]float64 `json:"baseColorFactor"`
+	MetallicFactor  float64    `json:"metallicFactor"`
+	RoughnessFactor float64    `json:"roughnessFactor"`
+}
+
+type gltfBuffer struct {
+	ByteLength int `json:"byteLength"`
+}
+
+type gltfBufferView struct {
+	Buffer     int `json:"buffer"`
+	ByteOffset int `json:"byteOffset"`
+	ByteLength int `json:"byteLength"`
+	Target     int `json:"target"`
+}
+
+type gltfAccessor struct {
+	BufferView    int       `json:"bufferView"`
+	ComponentType int       `json:"componentType"`
+	Count         int       `json:"count"`
+	Type          string    `json:"type"`
+	Min           []float32 `json:"min,omitempty"`
+	Max           []float32 `json:"max,omitempty"`
+}
+
+const (
+	gltfFloat        = 5126
+	gltfUnsignedInt  = 5125
+	gltfArrayBuffer  = 34962
+	gltfElementArray = 34963
+
+	glbMagic     = 0x46546c67 // "glTF"
+	glbChunkJSON = 0x4e4f534a // "JSON"
+	glbChunkBIN  = 0x004e4942 // "BIN\x00"
+)
+
+// srgbToLinear converts a color channel for glTF, whose color factors are
+// linear
+func srgbToLinear(c uint8) float64 {
+	v := float64(c) / 255
+	if v <= 0.04045 {
+		return v / 12.92
+	}
+	return math.Pow((v+0.055)/1.055, 2.4)
+}
+
+// ExportGLB writes the meshed voxels in the box between the world positions,
+// inclusive, as a binary glTF 2.0 file, with one primitive and material per
+// VoxelType. greedy merges coplanar faces into larger quads
+func (w *World) ExportGLB(out io.Writer, from, to BlockPos, greedy bool) error {
+	geometries := w.exportGeometry(from, to, greedy)
+
+	doc := gltfDocument{
+		Asset:  gltfAsset{Version: "2.0", Generator: "go-voxel"},
+		Scenes: []gltfScene{{}},
+	}
+
+	// A mesh needs at least one primitive, so an empty export is just an
+	// empty scene
+	if len(geometries) > 0 {
+		doc.Scenes[0].Nodes = []int{0}
+		doc.Nodes = []gltfNode{{Mesh: 0}}
+		doc.Meshes = []gltfMesh{{}}
+	}
+
+	var bin bytes.Buffer
+	addView := func(data any, target int) int {
+		offset := bin.Len()
+		binary.Write(&bin, binary.LittleEndian, data)
+		doc.BufferViews = append(doc.BufferViews, gltfBufferView{
+			ByteOffset: offset,
+			ByteLength: bin.Len() - offset,
+			Target:     target,
+		})
+		return len(doc.BufferViews) - 1
+	}
+	addAccessor := func(accessor gltfAccessor) int {
+		doc.Accessors = append(doc.Accessors, accessor)
+		return len(doc.Accessors) - 1
+	}
+
+	for _, geometry := range geometries {
+		vertices := len(geometry.Positions) / 3
+
+		// POSITION accessors must have bounds
+		lo := []float32{math.MaxFloat32, math.MaxFloat32, math.MaxFloat32}
+		hi := []float32{-math.MaxFloat32, -math.MaxFloat32, -math.MaxFloat32}
+		for v := range vertices {
+			for axis := range 3 {
+				lo[axis] = min(lo[axis], geometry.Positions[v*3+axis])
+				hi[axis] = max(hi[axis], geometry.Positions[v*3+axis])
+			}
+		}
+
+		position := addAccessor(gltfAccessor{
+			BufferView:    addView(geometry.Positions, gltfArrayBuffer),
+			ComponentType: gltfFloat,
+			Count:         vertices,
+			Type:          "VEC3",
+			Min:           lo,
+			Max:           hi,
+		})
+		normal := addAccessor(gltfAccessor{
+			BufferView:    addView(geometry.Normals, gltfArrayBuffer),
+			ComponentType: gltfFloat,
+			Count:         vertices,
+			Type:          "VEC3",
+		})
+		indices := addAccessor(gltfAccessor{
+			BufferView:    addView(geometry.Indices, gltfElementArray),
+			ComponentType: gltfUnsignedInt,
+			Count:         len(geometry.Indices),
+			Type:          "SCALAR",
+		})
+
+		c := w.Blocks.Color(geometry.Type)
+		doc.Materials = append(doc.Materials, gltfMaterial{
+			Name: w.Blocks.Name(geometry.Type),
+			PBR: gltfPBR{
+				BaseColorFactor: [4]float64{srgbToLinear(c.R), srgbToLinear(c.G), srgbToLinear(c.B), float64(c.A) / 255},
+				RoughnessFactor: 1,
+			},
+		})
+
+		doc.Meshes[0].Primitives = append(doc.Meshes[0].Primitives, gltfPrimitive{
+			Attributes: map[string]int{"POSITION": position, "NORMAL": normal},
+			Indices:    indices,
+			Material:   len(doc.Materials) - 1,
+		})
+	}
+	if bin.Len() > 0 {
+		doc.Buffers = []gltfBuffer{{ByteLength: bin.Len()}}
+	}
+
+	jsonData, err := json.Marshal(doc)
+	if err != nil {
+		return fmt.Errorf("encoding gltf: %w", err)
+	}
+
+	// Chunks are padded to 4 bytes, JSON with spaces and binary with zeros
+	for len(jsonData)%4 != 0 {
+		jsonData = append(jsonData, ' ')
+	}
+	for bin.Len()%4 != 0 {
+		bin.WriteByte(0)
+	}
+
+	length := 12 + 8 + len(jsonData)
+	if bin.Len() > 0 {
+		length += 8 + bin.Len()
+	}
+
+	var glb bytes.Buffer
+	binary.Write(&glb, binary.LittleEndian, []uint32{glbMagic, 2, uint32(length)})
+	binary.Write(&glb, binary.LittleEndian, []uint32{uint32(len(jsonData)), glbChunkJSON})
+	glb.Write(jsonData)
+	if bin.Len() > 0 {
+		binary.Write(&glb, binary.LittleEndian, []uint32{uint32(bin.Len()), glbChunkBIN})
+		glb.Write(bin.Bytes())
+	}
+
+	if _, err := out.Write(glb.Bytes()); err != nil {
+		return fmt.Errorf("writing glb: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,241 @@
+package game
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// geometryBounds returns the area of the quads facing along each axis, and
+// the corners of the box around every vertex
+func geometryBounds(geometries []*typeGeometry) (area [3]float32, lo, hi [3]float32) {
+	lo = [3]float32{1e9, 1e9, 1e9}
+	hi = [3]float32{-1e9, -1e9, -1e9}
+	for _, g := range geometries {
+		for v := range len(g.Positions) / 3 {
+			for axis := range 3 {
+				lo[axis] = min(lo[axis], g.Positions[v*3+axis])
+				hi[axis] = max(hi[axis], g.Positions[v*3+axis])
+			}
+		}
+
+		for q := range len(g.Positions) / 12 {
+			mesh := ChunkMesh{Vertices: g.Positions[q*12 : q*12+12], Normals: g.Normals[q*12 : q*12+12], Types: []VoxelType{g.Type}}
+			for axis := range 3 {
+				area[axis] += faceArea(&mesh, axis)
+			}
+		}
+	}
+	return area, lo, hi
+}
+
+func TestChunksInBox(t *testing.T) {
+	world := newTestWorld(t, 0)
+
+	// None of the chunks are loaded
+	got := world.ChunksInBox(BlockPos{X: 16, Y: 0, Z: -1}, BlockPos{X: -1, Y: 15, Z: 0})
+	want := []ChunkCoord{{-1, 0, -1}, {-1, 0, 0}, {0, 0, -1}, {0, 0, 0}, {1, 0, -1}, {1, 0, 0}}
+	if !slices.Equal(got, want) {
+		t.Errorf("ChunksInBox = %v, want %v", got, want)
+	}
+}
+
+func TestExportGeometryClipsToBox(t *testing.T) {
+	tests := []struct {
+		name     string
+		from, to BlockPos
+		greedy   bool
+		quads    int
+	}{
+		// A 3x2x2 block cut out of the stone below y = 1
+		{"culled", BlockPos{X: 0, Y: 0, Z: 0}, BlockPos{X: 2, Y: 1, Z: 1}, false, 32},
+		{"greedy", BlockPos{X: 2, Y: 1, Z: 1}, BlockPos{X: 0, Y: 0, Z: 0}, true, 6},
+
+		// Across the corner of eight chunks, each meshed separately with
+		// just its three faces on the outside of the box
+		{"chunk border", BlockPos{X: -1, Y: -1, Z: -1}, BlockPos{X: 1, Y: 0, Z: 0}, true, 8 * 3},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			world := newTestWorld(t, 1)
+			geometries := world.exportGeometry(test.from, test.to, test.greedy)
+
+			if len(geometries) != 1 || geometries[0].Type != stone {
+				t.Fatalf("exported %d geometries, want just stone", len(geometries))
+			}
+			if quads := len(geometries[0].Indices) / 6; quads != test.quads {
+				t.Errorf("exported %d quads, want %d", quads, test.quads)
+			}
+
+			// Closed off on every side, and no bigger than the box
+			lo, hi := boxCorners(test.from, test.to)
+			size := [3]float32{float32(hi.X - lo.X + 1), float32(hi.Y - lo.Y + 1), float32(hi.Z - lo.Z + 1)}
+			area, gotLo, gotHi := geometryBounds(geometries)
+			wantArea := [3]float32{2 * size[1] * size[2], 2 * size[0] * size[2], 2 * size[0] * size[1]}
+			if area != wantArea {
+				t.Errorf("face area along each axis = %v, want %v", area, wantArea)
+			}
+			wantMin := [3]float32{float32(lo.X) - 0.5, float32(lo.Y) - 0.5, float32(lo.Z) - 0.5}
+			wantMax := [3]float32{float32(hi.X) + 0.5, float32(hi.Y) + 0.5, float32(hi.Z) + 0.5}
+			if gotLo != wantMin || gotHi != wantMax {
+				t.Errorf("geometry spans %v to %v, want %v to %v", gotLo, gotHi, wantMin, wantMax)
+			}
+
+			if len(world.Chunks) != 0 {
+				t.Errorf("exporting loaded %d chunks into the world", len(world.Chunks))
+			}
+		})
+	}
+}
+
+func TestExportGeometryEdits(t *testing.T) {
+	world := newTestWorld(t, 0)
+	world.SetVoxel(BlockPos{X: 1, Y: 0, Z: 1}, air)
+	world.SetVoxel(BlockPos{X: 1, Y: 1, Z: 1}, dirt)
+
+	// The dirt sits on top of the box's stone, so is outside it
+	geometries := world.exportGeometry(BlockPos{X: 0, Y: 0, Z: 0}, BlockPos{X: 2, Y: 0, Z: 2}, false)
+	if len(geometries) != 1 || geometries[0].Type != stone {
+		t.Fatalf("exported %d geometries, want just stone", len(geometries))
+	}
+
+	// 8 stone voxels around the hole, with the hole's 4 walls showing
+	area, _, _ := geometryBounds(geometries)
+	if want := [3]float32{2*3 + 2, 2 * 8, 2*3 + 2}; area != want {
+		t.Errorf("face area along each axis = %v, want %v", area, want)
+	}
+
+	geometries = world.exportGeometry(BlockPos{X: 0, Y: 0, Z: 0}, BlockPos{X: 2, Y: 1, Z: 2}, true)
+	if len(geometries) != 2 || geometries[0].Type != dirt || geometries[1].Type != stone {
+		t.Fatalf("exported %d geometries, want dirt then stone", len(geometries))
+	}
+	if quads := len(geometries[0].Indices) / 6; quads != 6 {
+		t.Errorf("exported %d dirt quads, want 6 with the bottom showing through the hole", quads)
+	}
+}
+
+func TestExportOBJ(t *testing.T) {
+	world := newTestWorld(t, 0)
+	world.SetVoxel(BlockPos{X: 0, Y: 1, Z: 0}, dirt)
+
+	var obj, mtl bytes.Buffer
+	if err := world.ExportOBJ(&obj, &mtl, "world.mtl", BlockPos{X: 0, Y: 0, Z: 0}, BlockPos{X: 0, Y: 1, Z: 0}, false); err != nil {
+		t.Fatal(err)
+	}
+
+	counts := make(map[string]int)
+	for _, line := range strings.Split(obj.String(), "\n") {
+		if field, _, ok := strings.Cut(line, " "); ok {
+			counts[field]++
+		}
+	}
+
+	// Two voxels with 5 faces each
+	want := map[string]int{"mtllib": 1, "o": 2, "usemtl": 2, "v": 40, "vn": 40, "f": 20}
+	for field, n := range want {
+		if counts[field] != n {
+			t.Errorf("%d %q lines, want %d", counts[field], field, n)
+		}
+	}
+	if !strings.HasPrefix(obj.String(), "mtllib world.mtl\n") {
+		t.Errorf("obj doesn't start with its mtllib")
+	}
+	for _, name := range []string{"dirt", "stone"} {
+		if !strings.Contains(mtl.String(), "newmtl "+name+"\n") {
+			t.Errorf("mtl has no material for %s", name)
+		}
+	}
+}
+
+func TestObjName(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"stone", "stone"},
+		{"Mossy_Stone-2.old", "Mossy_Stone-2.old"},
+		{"red sand", "red_sand"},
+		{"glass\nnewmtl evil", "glass_newmtl_evil"},
+		{"tab\there#comment", "tab_here_comment"},
+		{"héllo", "h_llo"},
+		{"", "_"},
+	}
+
+	for _, tt := range tests {
+		if got := objName(tt.name); got != tt.want {
+			t.Errorf("objName(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestExportOBJSanitisesNames(t *testing.T) {
+	world := newTestWorld(t, 0)
+	blocks, err := LoadBlockRegistry(strings.NewReader(`[
+		{"name": "grey stone\nnewmtl x", "id": 3, "color": "#7d7d7d", "solid": true}
+	]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	world.Blocks = blocks
+
+	var obj, mtl bytes.Buffer
+	if err := world.ExportOBJ(&obj, &mtl, "world.mtl", BlockPos{}, BlockPos{}, true); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(obj.String(), "\nusemtl grey_stone_newmtl_x\n") {
+		t.Errorf("obj doesn't use the sanitised name:\n%s", obj.String())
+	}
+	if mtl.String() != "newmtl grey_stone_newmtl_x\nKd 0.4902 0.4902 0.4902\nd 1.0000\n\n" {
+		t.Errorf("mtl is %q", mtl.String())
+	}
+}
+
+func TestExportGLB(t *testing.T) {
+	world := newTestWorld(t, 1)
+
+	var glb bytes.Buffer
+	if err := world.ExportGLB(&glb, BlockPos{X: 0, Y: 0, Z: 0}, BlockPos{X: 2, Y: 1, Z: 1}, true); err != nil {
+		t.Fatal(err)
+	}
+	data := glb.Bytes()
+
+	header := make([]uint32, 5)
+	if err := binary.Read(bytes.NewReader(data), binary.LittleEndian, header); err != nil {
+		t.Fatal(err)
+	}
+	if header[0] != glbMagic || header[1] != 2 || int(header[2]) != len(data) || header[4] != glbChunkJSON {
+		t.Fatalf("bad glb header %x for %d bytes", header, len(data))
+	}
+
+	var doc gltfDocument
+	if err := json.Unmarshal(data[20:20+header[3]], &doc); err != nil {
+		t.Fatal(err)
+	}
+	if len(doc.Meshes) != 1 || len(doc.Meshes[0].Primitives) != 1 || len(doc.Materials) != 1 {
+		t.Fatalf("glb has %d meshes and %d materials, want one stone primitive", len(doc.Meshes), len(doc.Materials))
+	}
+
+	position := doc.Accessors[doc.Meshes[0].Primitives[0].Attributes["POSITION"]]
+	if position.Count != 6*4 {
+		t.Errorf("%d vertices, want %d", position.Count, 6*4)
+	}
+	wantMin, wantMax := []float32{-0.5, -0.5, -0.5}, []float32{2.5, 1.5, 1.5}
+	for axis := range 3 {
+		if position.Min[axis] != wantMin[axis] || position.Max[axis] != wantMax[axis] {
+			t.Errorf("positions span %v to %v, want %v to %v", position.Min, position.Max, wantMin, wantMax)
+			break
+		}
+	}
+
+	// A box of air is an empty scene
+	glb.Reset()
+	if err := world.ExportGLB(&glb, BlockPos{Y: 10}, BlockPos{Y: 20}, true); err != nil {
+		t.Fatal(err)
+	}
+	if glb.Len()%4 != 0 || binary.LittleEndian.Uint32(glb.Bytes()[8:]) != uint32(glb.Len()) {
+		t.Errorf("empty glb is %d bytes with a bad length", glb.Len())
+	}
+}
@@ -0,0 +1,111 @@
+package game
+
+import (
+	"math"
+)
+
+// Fractal configures how octaves of a NoiseSource are layered
+type Fractal struct {
+	Octaves int
+
+	// Frequency of the first octave
+	Frequency float64
+
+	// Frequency multiplier between octaves
+	Lacunarity float64
+
+	// Amplitude multiplier between octaves
+	Persistence float64
+}
+
+// DefaultFractal is a good starting point for terrain
+func DefaultFractal(octaves int) Fractal {
+	return Fractal{
+		Octaves:     octaves,
+		Frequency:   1,
+		Lacunarity:  2,
+		Persistence: 0.5,
+	}
+}
+
+// octaves calls sample for every octave with its frequency and amplitude,
+// returning the sum of the amplitudes so the result can be normalized
+func (f Fractal) octaves(sample func(frequency, amplitude float64)) float64 {
+	frequency, amplitude := f.Frequency, 1.0
+	var total float64
+
+	for range f.Octaves {
+		sample(frequency, amplitude)
+		total += amplitude
+
+		frequency *= f.Lacunarity
+		amplitude *= f.Persistence
+	}
+
+	return total
+}
+
+// FBM is fractal Brownian motion, the sum of increasingly detailed and
+// quieter octaves of the source
+type FBM struct {
+	Source NoiseSource
+	Fractal
+}
+
+func (n FBM) Noise(x, y, z float64) float64 {
+	var sum float64
+	total := n.octaves(func(frequency, amplitude float64) {
+		sum += n.Source.Noise(x*frequency, y*frequency, z*frequency) * amplitude
+	})
+	if total == 0 {
+		return 0
+	}
+	return sum / total
+}
+
+// Billow folds each octave with an absolute value to give puffy, rounded
+// shapes like hills and clouds
+type Billow struct {
+	Source NoiseSource
+	Fractal
+}
+
+func (n Billow) Noise(x, y, z float64) float64 {
+	var sum float64
+	total := n.octaves(func(frequency, amplitude float64) {
+		signal := math.Abs(n.Source.Noise(x*frequency, y*frequency, z*frequency))
+		sum += (2*signal - 1) * amplitude
+	})
+	if total == 0 {
+		return 0
+	}
+	return sum / total
+}
+
+// Ridged is a ridged multifractal giving sharp crests like mountain ranges.
+// Each octave is weighted by the previous one so detail gathers on the ridges
+type Ridged struct {
+	Source NoiseSource
+	Fractal
+
+	// Gain controls how strongly the previous octave weights the next
+	Gain float64
+}
+
+func (n Ridged) Noise(x, y, z float64) float64 {
+	var sum float64
+	weight := 1.0
+	total := n.octaves(func(frequency, amplitude float64) {
+		signal := 1 - math.Abs(n.Source.Noise(x*frequency, y*frequency, z*frequency))
+		signal *= signal * weight
+
+		weight = min(max(signal*n.Gain, 0), 1)
+		sum += signal * amplitude
+	})
+	if total == 0 {
+		return 0
+	}
+
+	// The sum is in [0, total], map it to [-1, 1] like the other sources
+	return 2*sum/total - 1
+}
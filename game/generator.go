@@ -0,0 +1,113 @@
+package game
+
+import (
+	"math"
+)
+
+// ChunkGenerator fills a newly created chunk with voxels. Generators sample
+// at world coordinates so terrain is continuous across chunk borders, and
+// must be safe to call from multiple goroutines at once
+type ChunkGenerator interface {
+	Generate(c *Chunk)
+}
+
+// GenerationPipeline runs each stage in order, such as base terrain followed
+// by cave carving
+type GenerationPipeline []ChunkGenerator
+
+func (p GenerationPipeline) Generate(c *Chunk) {
+	for _, stage := range p {
+		stage.Generate(c)
+	}
+}
+
+// NewDefaultGenerator creates the standard terrain pipeline for the seed
+func NewDefaultGenerator(seed uint64) GenerationPipeline {
+	terrain := NewHeightmapGenerator(seed)
+
+	return GenerationPipeline{
+		terrain,
+		NewCaveCarver(seed, terrain),
+	}
+}
+
+// FlatGenerator fills every column up to Height with a single voxel type
+type FlatGenerator struct {
+	Height int
+	Type   VoxelType
+}
+
+func (g FlatGenerator) Generate(c *Chunk) {
+	for x := range int(chunkLength) {
+		for z := range int(chunkLength) {
+			fillColumn(c, x, z, g.Height, func(int) VoxelType { return g.Type })
+		}
+	}
+}
+
+// HeightmapGenerator builds rolling terrain from 2D noise, with a layer of
+// grass on top of dirt on top of stone
+type HeightmapGenerator struct {
+	Noise NoiseSource
+
+	// Height of the terrain where the noise is 0, and how far it varies
+	BaseHeight, Amplitude float64
+
+	// Number of dirt voxels beneath the grass
+	DirtDepth int
+}
+
+// Create a new heightmap generator with terrain settings for the seed
+func NewHeightmapGenerator(seed uint64) *HeightmapGenerator {
+	fractal := DefaultFractal(4)
+	fractal.Frequency = 1.0 / 64
+
+	return &HeightmapGenerator{
+		Noise: FBM{
+			Source:  NewPerlin(seed),
+			Fractal: fractal,
+		},
+		BaseHeight: 48,
+		Amplitude:  32,
+		DirtDepth:  3,
+	}
+}
+
+// Height returns the world y of the top voxel of the column at world x, z
+func (g *HeightmapGenerator) Height(x, z int) int {
+	n := g.Noise.Noise(float64(x), 0, float64(z))
+	return int(math.Round(g.BaseHeight + n*g.Amplitude))
+}
+
+func (g *HeightmapGenerator) Generate(c *Chunk) {
+	origin := c.Coord.Origin()
+	for x := range int(chunkLength) {
+		for z := range int(chunkLength) {
+			height := g.Height(origin.X+x, origin.Z+z)
+
+			fillColumn(c, x, z, height, func(y int) VoxelType {
+				switch {
+				case y == height:
+					return grass
+				case y >= height-g.DirtDepth:
+					return dirt
+				default:
+					return stone
+				}
+			})
+		}
+	}
+}
+
+// fillColumn sets the voxels of the chunk column at local x, z from the
+// bottom of the world up to and including world y height
+func fillColumn(c *Chunk, x, z, height int, voxelType func(y int) VoxelType) {
+	originY := c.Coord.Origin().Y
+	for y := range int(chunkHeight) {
+		worldY := originY + y
+		if worldY > height {
+			break
+		}
+		c.setVoxel(x, y, z, voxelType(worldY))
+	}
+}
@@ -0,0 +1,110 @@
+package game
+
+import (
+	"testing"
+)
+
+// generatedColumn is a column of sections filled in by the generator, with
+// the world y of the top of each of its columns
+type generatedColumn struct {
+	sections []*Chunk
+	height   [chunkLength][chunkLength]int
+}
+
+func generateColumn(generator ChunkGenerator, x, z int32) *generatedColumn {
+	column := &generatedColumn{}
+	for x := range column.height {
+		for z := range column.height[x] {
+			column.height[x][z] = -1
+		}
+	}
+
+	for y := range defaultWorldSections {
+		chunk := NewChunk(ChunkCoord{X: x, Y: int32(y), Z: z})
+		generator.Generate(&chunk)
+		column.sections = append(column.sections, &chunk)
+
+		for x := range int(chunkLength) {
+			for y := range int(chunkHeight) {
+				for z := range int(chunkLength) {
+					if chunk.voxelType(x, y, z) != air {
+						column.height[x][z] = chunk.Coord.Block(x, y, z).Y
+					}
+				}
+			}
+		}
+	}
+	return column
+}
+
+// voxelType returns the voxel at local x, z and world y
+func (c *generatedColumn) voxelType(x, y, z int) VoxelType {
+	return c.sections[y/int(chunkHeight)].voxelType(x, y%int(chunkHeight), z)
+}
+
+func TestHeightmapMatchesAcrossChunks(t *testing.T) {
+	generator := NewHeightmapGenerator(99)
+
+	// Every column follows the heightmap at its world position, whichever
+	// chunk it's in
+	columns := make(map[[2]int32]*generatedColumn)
+	for _, pos := range [][2]int32{{0, 0}, {1, 0}, {-1, 0}, {0, -1}} {
+		column := generateColumn(generator, pos[0], pos[1])
+		columns[pos] = column
+
+		for x := range int(chunkLength) {
+			for z := range int(chunkLength) {
+				block := ChunkCoord{X: pos[0], Z: pos[1]}.Block(x, 0, z)
+				want := generator.Height(block.X, block.Z)
+				if got := column.height[x][z]; got != want {
+					t.Fatalf("column at %d, %d is %d high, want %d", block.X, block.Z, got, want)
+				}
+			}
+		}
+	}
+
+	// The last column of one chunk and the first of the next are neighbours,
+	// so can only be a step or two apart
+	borders := []struct {
+		a, b   [2]int32
+		alongX bool
+	}{
+		{[2]int32{0, 0}, [2]int32{1, 0}, true},
+		{[2]int32{-1, 0}, [2]int32{0, 0}, true},
+		{[2]int32{0, -1}, [2]int32{0, 0}, false},
+	}
+	for _, border := range borders {
+		for i := range int(chunkLength) {
+			a, b := columns[border.a].height[15][i], columns[border.b].height[0][i]
+			if !border.alongX {
+				a, b = columns[border.a].height[i][15], columns[border.b].height[i][0]
+			}
+			if a-b > 2 || b-a > 2 {
+				t.Errorf("columns either side of the border between %v and %v are %d and %d high", border.a, border.b, a, b)
+			}
+		}
+	}
+}
+
+func TestHeightmapLayers(t *testing.T) {
+	generator := NewHeightmapGenerator(3)
+	column := generateColumn(generator, 2, -3)
+
+	for x := range int(chunkLength) {
+		for z := range int(chunkLength) {
+			height := column.height[x][z]
+			for y := range height + 1 {
+				want := stone
+				switch {
+				case y == height:
+					want = grass
+				case y >= height-generator.DirtDepth:
+					want = dirt
+				}
+				if got := column.voxelType(x, y, z); got != want {
+					t.Fatalf("voxel %d below the top of column %d, %d is %v, want %v", height-y, x, z, got, want)
+				}
+			}
+		}
+	}
+}
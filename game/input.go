@@ -1,6 +1,8 @@
 package game
 
 import (
+	"slices"
+
 	rl "github.com/gen2brain/raylib-go/raylib"
 )
 
@@ -10,126 +12,36 @@ const (
 
 type InputHandler struct {
 	engine *Engine
+
+	// Selected is the type of block placed with right click
+	Selected VoxelType
+
+	// Clicks and toggles wait here until the next tick reads them, so none
+	// are lost on frames that don't tick
+	breakQueued, placeQueued, toggleQueued bool
 }
 
 func NewInputHandler(e *Engine) InputHandler {
 	return InputHandler{
 		engine: e,
+		// Registries always define at least one block
+		Selected: e.World.Blocks.Types()[0],
 	}
 }
 
-// Handle keyboard input
+// Handle keyboard and mouse input. The camera turns straight away while
+// everything else waits for the next TickInput
 func (ih *InputHandler) Handle() {
-	speed := float32(0.005)
-	if rl.IsKeyDown(rl.KeyLeftShift) {
-		speed *= 2
+	if rl.IsKeyPressed(rl.KeyF) {
+		ih.toggleQueued = !ih.toggleQueued
 	}
-
-	// Forward
-	if rl.IsKeyDown(rl.KeyW) {
-		ih.engine.Camera3D.Position = rl.Vector3Add(
-			ih.engine.Camera3D.Position,
-			rl.Vector3Scale(
-				rl.Vector3Subtract(ih.engine.Camera3D.Target, ih.engine.Camera3D.Position),
-				speed,
-			),
-		)
-		ih.engine.Camera3D.Target = rl.Vector3Add(
-			ih.engine.Camera3D.Target,
-			rl.Vector3Scale(
-				rl.Vector3Subtract(ih.engine.Camera3D.Target, ih.engine.Camera3D.Position),
-				speed,
-			),
-		)
-		// This moves the camera forward by:
-		// 1. Calculating the direction vector (Target - Position)
-		// 2. Scaling this vector by the speed
-		// 3. Adding the scaled vector to the current position
-	}
-
-	// Backward
-	if rl.IsKeyDown(rl.KeyS) {
-		ih.engine.Camera3D.Position = rl.Vector3Subtract(
-			ih.engine.Camera3D.Position,
-			rl.Vector3Scale(
-				rl.Vector3Subtract(ih.engine.Camera3D.Target, ih.engine.Camera3D.Position),
-				speed,
-			),
-		)
-		ih.engine.Camera3D.Target = rl.Vector3Subtract(
-			ih.engine.Camera3D.Target,
-			rl.Vector3Scale(
-				rl.Vector3Subtract(ih.engine.Camera3D.Target, ih.engine.Camera3D.Position),
-				speed,
-			),
-		)
-		// This moves the camera backward by:
-		// 1. Calculating the direction vector (Target - Position)
-		// 2. Scaling this vector by the speed
-		// 3. Subtracting the scaled vector from the current position
+	if rl.IsMouseButtonPressed(rl.MouseButtonLeft) {
+		ih.breakQueued = true
 	}
-
-	// Left
-	if rl.IsKeyDown(rl.KeyA) {
-		ih.engine.Camera3D.Position = rl.Vector3Subtract(
-			ih.engine.Camera3D.Position,
-			rl.Vector3Scale(
-				rl.Vector3CrossProduct(
-					rl.Vector3Subtract(ih.engine.Camera3D.Target, ih.engine.Camera3D.Position),
-					ih.engine.Camera3D.Up,
-				),
-				speed,
-			),
-		)
-		ih.engine.Camera3D.Target = rl.Vector3Subtract(
-			ih.engine.Camera3D.Target,
-			rl.Vector3Scale(
-				rl.Vector3CrossProduct(
-					rl.Vector3Subtract(ih.engine.Camera3D.Target, ih.engine.Camera3D.Position),
-					ih.engine.Camera3D.Up,
-				),
-				speed,
-			),
-		)
-	}
-
-	// Right
-	if rl.IsKeyDown(rl.KeyD) {
-		ih.engine.Camera3D.Position = rl.Vector3Add(
-			ih.engine.Camera3D.Position,
-			rl.Vector3Scale(
-				rl.Vector3CrossProduct(
-					rl.Vector3Subtract(ih.engine.Camera3D.Target, ih.engine.Camera3D.Position),
-					ih.engine.Camera3D.Up,
-				),
-				speed,
-			),
-		)
-		ih.engine.Camera3D.Target = rl.Vector3Add(
-			ih.engine.Camera3D.Target,
-			rl.Vector3Scale(
-				rl.Vector3CrossProduct(
-					rl.Vector3Subtract(ih.engine.Camera3D.Target, ih.engine.Camera3D.Position),
-					ih.engine.Camera3D.Up,
-				),
-				speed,
-			),
-		)
-	}
-
-	// Up
-	if rl.IsKeyDown(rl.KeySpace) {
-		upVector := rl.Vector3Scale(ih.engine.Camera3D.Up, speed*10)
-		ih.engine.Camera3D.Position = rl.Vector3Add(ih.engine.Camera3D.Position, upVector)
-		ih.engine.Camera3D.Target = rl.Vector3Add(ih.engine.Camera3D.Target, upVector)
-	}
-
-	// Down
-	if rl.IsKeyDown(rl.KeyLeftAlt) {
-		downVector := rl.Vector3Scale(ih.engine.Camera3D.Up, -speed*10)
-		ih.engine.Camera3D.Position = rl.Vector3Add(ih.engine.Camera3D.Position, downVector)
-		ih.engine.Camera3D.Target = rl.Vector3Add(ih.engine.Camera3D.Target, downVector)
+	if rl.IsMouseButtonPressed(rl.MouseButtonRight) {
+		ih.placeQueued = true
 	}
+	ih.selectBlock()
 
 	// Mouse Camera rotation
 	mousePositionDelta := rl.GetMouseDelta()
@@ -174,3 +86,37 @@ func (ih *InputHandler) Handle() {
 	rl.HideCursor()
 	rl.SetMousePosition(rl.GetScreenWidth()/2, rl.GetScreenHeight()/2)
 }
+
+// TickInput returns the input for the next tick, emptying the queued clicks
+func (ih *InputHandler) TickInput() TickInput {
+	camera := ih.engine.Camera3D
+	in := TickInput{
+		Move:          readMoveInput(),
+		Look:          rl.Vector3Subtract(camera.Target, camera.Position),
+		Break:         ih.breakQueued,
+		Place:         ih.placeQueued,
+		Selected:      ih.Selected,
+		ToggleWalking: ih.toggleQueued,
+	}
+	ih.breakQueued, ih.placeQueued, ih.toggleQueued = false, false, false
+	return in
+}
+
+// selectBlock chooses the block placed with the number keys or mouse wheel
+func (ih *InputHandler) selectBlock() {
+	types := ih.engine.World.Blocks.Types()
+
+	for i := range min(len(types), 9) {
+		if rl.IsKeyPressed(rl.KeyOne + int32(i)) {
+			ih.Selected = types[i]
+		}
+	}
+	if wheel := rl.GetMouseWheelMove(); wheel != 0 {
+		i := max(slices.Index(types, ih.Selected), 0)
+		step := 1
+		if wheel < 0 {
+			step = -1
+		}
+		ih.Selected = types[(i+step+len(types))%len(types)]
+	}
+}
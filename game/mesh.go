@@ -0,0 +1,258 @@
+package game
+
+import (
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// ChunkMesh is the geometry of a chunk in chunk local space, where the voxel
+// at (x, y, z) spans from (x, y, z) to (x+1, y+1, z+1). Texcoords count
+// voxels across the face rather than pointing into the atlas, so a texture
+// can repeat across a merged quad, and Tiles holds the atlas rectangle
+// (u0, v0, u1, v1) each repetition is drawn from. The chunk shader wraps one
+// into the other
+type ChunkMesh struct {
+	Vertices  []float32   // xyz per vertex
+	Normals   []float32   // xyz per vertex
+	Texcoords []float32   // uv per vertex, only when built with an atlas
+	Tiles     []float32   // atlas rectangle per vertex, only with an atlas
+	Indices   []uint16    // two triangles per quad
+	Types     []VoxelType // one per quad
+}
+
+// QuadCount is the number of quads (faces) in the mesh
+func (m *ChunkMesh) QuadCount() int {
+	return len(m.Types)
+}
+
+// VoxelLookup returns the type of the voxel at a chunk local position, which
+// may fall outside the chunk so faces on the chunk border can be culled
+// against the neighbouring chunks
+type VoxelLookup func(x, y, z int) VoxelType
+
+// chunkDims is the size of a chunk along x, y and z
+var chunkDims = [3]int{int(chunkLength), int(chunkHeight), int(chunkLength)}
+
+// addQuad appends a quad lying in the plane where axis d is at position
+// plane, starting at (u0, v0) and spanning du by dv on the other two axes.
+// positive decides whether the quad faces along +d or -d. The texture for the
+// face is looked up in the atlas if there is one, and repeats once per voxel
+func (m *ChunkMesh) addQuad(d, plane, u0, v0, du, dv int, positive bool, t VoxelType, atlas *BlockAtlas) {
+	u, v := (d+1)%3, (d+2)%3
+
+	type vertex struct {
+		pos  [3]float32
+		s, t float32 // position across the texture
+	}
+
+	corner := func(a, b int) vertex {
+		var p [3]float32
+		p[d] = float32(plane)
+		p[u] = float32(u0 + a)
+		p[v] = float32(v0 + b)
+
+		// Keep textures upright on the sides, where y is u for x facing
+		// quads and v for z facing quads. Image rows go down
+		s, tt := float32(a), float32(b)
+		switch d {
+		case 0:
+			s, tt = tt, float32(du)-s
+		case 2:
+			tt = float32(dv) - tt
+		}
+		return vertex{pos: p, s: s, t: tt}
+	}
+
+	// Counter clockwise when looking at the front of the face
+	corners := [4]vertex{corner(0, 0), corner(du, 0), corner(du, dv), corner(0, dv)}
+	if !positive {
+		corners[1], corners[3] = corners[3], corners[1]
+	}
+
+	if atlas != nil {
+		face := faceSide
+		if d == 1 {
+			face = faceBottom
+			if positive {
+				face = faceTop
+			}
+		}
+		uv := atlas.faceUV(t, face)
+
+		for _, c := range corners {
+			m.Texcoords = append(m.Texcoords, c.s, c.t)
+			m.Tiles = append(m.Tiles, uv[0], uv[1], uv[2], uv[3])
+		}
+	}
+
+	var normal [3]float32
+	normal[d] = 1
+	if !positive {
+		normal[d] = -1
+	}
+
+	base := uint16(len(m.Vertices) / 3)
+	for _, c := range corners {
+		m.Vertices = append(m.Vertices, c.pos[0], c.pos[1], c.pos[2])
+		m.Normals = append(m.Normals, normal[0], normal[1], normal[2])
+	}
+	m.Indices = append(m.Indices, base, base+1, base+2, base, base+2, base+3)
+	m.Types = append(m.Types, t)
+}
+
+// faceVisible reports whether the face of a voxel of type t is seen past
+// the neighbouring voxel
+func faceVisible(t, neighbour VoxelType, blocks *BlockRegistry) bool {
+	if neighbour == air {
+		return true
+	}
+	// Faces between two of the same transparent block, like water, are
+	// hidden too
+	return !blocks.Opaque(neighbour) && neighbour != t
+}
+
+// BuildCulledMesh builds a mesh with one quad per voxel face, skipping faces
+// that are hidden by an opaque neighbour. Faces are textured from the atlas
+// if it isn't nil
+func BuildCulledMesh(lookup VoxelLookup, blocks *BlockRegistry, atlas *BlockAtlas) ChunkMesh {
+	mesh := ChunkMesh{}
+
+	var pos, next [3]int
+	for pos[0] = 0; pos[0] < chunkDims[0]; pos[0]++ {
+		for pos[1] = 0; pos[1] < chunkDims[1]; pos[1]++ {
+			for pos[2] = 0; pos[2] < chunkDims[2]; pos[2]++ {
+				t := lookup(pos[0], pos[1], pos[2])
+				if t == air {
+					continue
+				}
+
+				for d := range 3 {
+					u, v := (d+1)%3, (d+2)%3
+
+					for _, positive := range []bool{true, false} {
+						next = pos
+						plane := pos[d]
+						if positive {
+							next[d]++
+							plane++
+						} else {
+							next[d]--
+						}
+
+						if !faceVisible(t, lookup(next[0], next[1], next[2]), blocks) {
+							continue
+						}
+						mesh.addQuad(d, plane, pos[u], pos[v], 1, 1, positive, t, atlas)
+					}
+				}
+			}
+		}
+	}
+
+	return mesh
+}
+
+// BuildGreedyMesh builds a mesh for the chunk where coplanar visible faces of
+// the same VoxelType are merged into as few quads as possible. Faces are
+// textured from the atlas if it isn't nil, repeating across merged quads
+func BuildGreedyMesh(lookup VoxelLookup, blocks *BlockRegistry, atlas *BlockAtlas) ChunkMesh {
+	mesh := ChunkMesh{}
+
+	for d := range 3 {
+		u, v := (d+1)%3, (d+2)%3
+		mask := make([]VoxelType, chunkDims[u]*chunkDims[v])
+
+		for _, positive := range []bool{true, false} {
+			step := -1
+			if positive {
+				step = 1
+			}
+
+			for slice := range chunkDims[d] {
+				// Mark the faces in this slice that can be seen
+				var pos, next [3]int
+				for j := range chunkDims[v] {
+					for i := range chunkDims[u] {
+						pos[d], pos[u], pos[v] = slice, i, j
+						next = pos
+						next[d] += step
+
+						t := lookup(pos[0], pos[1], pos[2])
+						if t != air && !faceVisible(t, lookup(next[0], next[1], next[2]), blocks) {
+							t = air
+						}
+						mask[i+j*chunkDims[u]] = t
+					}
+				}
+
+				plane := slice
+				if positive {
+					plane++
+				}
+
+				// Grow each unvisited face into the widest, then tallest,
+				// rectangle of the same type
+				for j := range chunkDims[v] {
+					for i := 0; i < chunkDims[u]; {
+						t := mask[i+j*chunkDims[u]]
+						if t == air {
+							i++
+							continue
+						}
+
+						width := 1
+						for i+width < chunkDims[u] && mask[i+width+j*chunkDims[u]] == t {
+							width++
+						}
+
+						height := 1
+					grow:
+						for j+height < chunkDims[v] {
+							for k := range width {
+								if mask[i+k+(j+height)*chunkDims[u]] != t {
+									break grow
+								}
+							}
+							height++
+						}
+
+						mesh.addQuad(d, plane, i, j, width, height, positive, t, atlas)
+
+						for h := range height {
+							for k := range width {
+								mask[i+k+(j+h)*chunkDims[u]] = air
+							}
+						}
+						i += width
+					}
+				}
+			}
+		}
+	}
+
+	return mesh
+}
+
+// toRaylib wraps the mesh data in an rl.Mesh that can be uploaded. The
+// ChunkMesh must outlive the rl.Mesh since the slices aren't copied
+func (m *ChunkMesh) toRaylib() rl.Mesh {
+	mesh := rl.Mesh{
+		VertexCount:   int32(len(m.Vertices) / 3),
+		TriangleCount: int32(len(m.Indices) / 3),
+	}
+	if len(m.Indices) == 0 {
+		return mesh
+	}
+
+	mesh.Vertices = &m.Vertices[0]
+	mesh.Normals = &m.Normals[0]
+	mesh.Indices = &m.Indices[0]
+	if len(m.Texcoords) > 0 {
+		mesh.Texcoords = &m.Texcoords[0]
+
+		// Chunks aren't normal mapped, so the tangent attribute is free to
+		// carry the atlas rectangles to the chunk shader
+		mesh.Tangents = &m.Tiles[0]
+	}
+
+	return mesh
+}
@@ -0,0 +1,302 @@
+package game
+
+import (
+	"strings"
+	"testing"
+)
+
+// testChunk is a chunk holding the voxels returned by the function
+func testChunk(voxels func(x, y, z int) VoxelType) *Chunk {
+	c := &Chunk{meshDirty: true}
+	for x := range int(chunkLength) {
+		for y := range int(chunkHeight) {
+			for z := range int(chunkLength) {
+				if t := voxels(x, y, z); t != air {
+					c.setVoxel(x, y, z, t)
+				}
+			}
+		}
+	}
+	return c
+}
+
+// layer fills the bottom layer of the chunk where the function is true
+func layer(t VoxelType, in func(x, z int) bool) func(x, y, z int) VoxelType {
+	return func(x, y, z int) VoxelType {
+		if y != 0 || !in(x, z) {
+			return air
+		}
+		return t
+	}
+}
+
+// slabLookup is a single layer of stone across the bottom of the chunk
+func slabLookup(x, y, z int) VoxelType {
+	if x < 0 || z < 0 || x >= int(chunkLength) || z >= int(chunkLength) || y != 0 {
+		return air
+	}
+	return stone
+}
+
+// quadTexcoords returns the range of the quad's texture coordinates
+func quadTexcoords(m *ChunkMesh, q int) (lo, hi [2]float32) {
+	lo, hi = [2]float32{1e9, 1e9}, [2]float32{-1e9, -1e9}
+	for v := q * 4; v < q*4+4; v++ {
+		for i := range 2 {
+			lo[i] = min(lo[i], m.Texcoords[v*2+i])
+			hi[i] = max(hi[i], m.Texcoords[v*2+i])
+		}
+	}
+	return lo, hi
+}
+
+// faceArea sums the area of the mesh's quads facing along the axis
+func faceArea(m *ChunkMesh, axis int) float32 {
+	var area float32
+	for q := range m.QuadCount() {
+		if m.Normals[q*4*3+axis] == 0 {
+			continue
+		}
+
+		// Opposite corners of the quad
+		a, c := m.Vertices[q*4*3:], m.Vertices[(q*4+2)*3:]
+		size := float32(1)
+		for i := range 3 {
+			if i != axis {
+				size *= max(a[i]-c[i], c[i]-a[i])
+			}
+		}
+		area += size
+	}
+	return area
+}
+
+func TestGreedyMeshQuadCount(t *testing.T) {
+	tests := []struct {
+		name   string
+		voxels func(x, y, z int) VoxelType
+		quads  int
+	}{
+		{"empty", func(x, y, z int) VoxelType { return air }, 0},
+		{"single voxel", layer(dirt, func(x, z int) bool { return x == 3 && z == 5 }), 6},
+		{"slab", layer(dirt, func(x, z int) bool { return true }), 6},
+		{"full chunk", func(x, y, z int) VoxelType { return dirt }, 6},
+
+		// The faces the two halves share are hidden, but their tops, bottoms
+		// and z sides aren't merged across the seam
+		{"two types", func(x, y, z int) VoxelType {
+			if y != 0 {
+				return air
+			}
+			if x < 8 {
+				return grass
+			}
+			return dirt
+		}, 2 + 2 + 2 + 4},
+
+		// Two rectangles on top and bottom, and one quad per straight run of
+		// the outline
+		{"L shape", layer(dirt, func(x, z int) bool {
+			return x < 4 && z < 4 && (x < 2 || z < 2)
+		}), 2 + 2 + 6},
+
+		// Voxels only touching at their edges don't merge at all
+		{"checkerboard", layer(dirt, func(x, z int) bool {
+			return x < 4 && z < 4 && (x+z)%2 == 0
+		}), 8 * 6},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mesh := BuildGreedyMesh(testChunk(test.voxels).voxelType, DefaultBlockRegistry(), nil)
+			if got := mesh.QuadCount(); got != test.quads {
+				t.Errorf("%d quads, want %d", got, test.quads)
+			}
+			if len(mesh.Vertices) != mesh.QuadCount()*4*3 || len(mesh.Normals) != len(mesh.Vertices) || len(mesh.Indices) != mesh.QuadCount()*6 {
+				t.Errorf("%d vertices, %d normals and %d indices for %d quads", len(mesh.Vertices)/3, len(mesh.Normals)/3, len(mesh.Indices), mesh.QuadCount())
+			}
+		})
+	}
+}
+
+func TestGreedyMeshKeepsTypesApart(t *testing.T) {
+	mesh := BuildGreedyMesh(testChunk(func(x, y, z int) VoxelType {
+		if y != 0 {
+			return air
+		}
+		if x < 8 {
+			return grass
+		}
+		return dirt
+	}).voxelType, DefaultBlockRegistry(), nil)
+
+	// Each type covers half of the slab's top
+	var area [3]float32
+	for q, t := range mesh.Types {
+		if mesh.Normals[q*12+1] != 1 {
+			continue
+		}
+		a, c := mesh.Vertices[q*12:], mesh.Vertices[q*12+6:]
+		area[t] += (c[0] - a[0]) * (c[2] - a[2])
+	}
+	if area[grass] != 8*16 || area[dirt] != 8*16 {
+		t.Errorf("top area is %v of grass and %v of dirt, want %v of each", area[grass], area[dirt], 8*16)
+	}
+}
+
+func TestCulledMeshDropsInteriorFaces(t *testing.T) {
+	tests := []struct {
+		name   string
+		voxels func(x, y, z int) VoxelType
+		quads  int
+	}{
+		{"single voxel", layer(dirt, func(x, z int) bool { return x == 3 && z == 5 }), 6},
+		{"two voxels", layer(dirt, func(x, z int) bool { return x == 3 && (z == 5 || z == 6) }), 10},
+		{"cube", func(x, y, z int) VoxelType {
+			if x < 2 && y < 2 && z < 2 {
+				return grass
+			}
+			return air
+		}, 6 * 4},
+
+		// Faces between different types are hidden too
+		{"two types", func(x, y, z int) VoxelType {
+			if x < 2 && y < 2 && z < 2 {
+				return VoxelType(1 + x)
+			}
+			return air
+		}, 6 * 4},
+
+		// Only the outside of a solid chunk is left
+		{"full chunk", func(x, y, z int) VoxelType { return dirt }, 6 * 16 * 16},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mesh := BuildCulledMesh(testChunk(test.voxels).voxelType, DefaultBlockRegistry(), nil)
+			if got := mesh.QuadCount(); got != test.quads {
+				t.Errorf("%d quads, want %d", got, test.quads)
+			}
+
+			// Culling never changes the faces on the outside
+			greedy := BuildGreedyMesh(testChunk(test.voxels).voxelType, DefaultBlockRegistry(), nil)
+			for axis := range 3 {
+				if c, g := faceArea(&mesh, axis), faceArea(&greedy, axis); c != g {
+					t.Errorf("culled and greedy face areas along axis %d are %v and %v", axis, c, g)
+				}
+			}
+		})
+	}
+}
+
+func TestMeshTransparentFaces(t *testing.T) {
+	blocks, err := LoadBlockRegistry(strings.NewReader(`[
+		{"name": "stone", "id": 3, "color": "#7d7d7d", "solid": true},
+		{"name": "glass", "id": 7, "color": "#ffffff40", "solid": true, "transparent": true},
+		{"name": "water", "id": 8, "color": "#2040ff80", "solid": false, "transparent": true}
+	]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		row   []VoxelType
+		quads int
+	}{
+		// Stone is seen through glass, but glass isn't seen through stone
+		{"stone and glass", []VoxelType{stone, 7}, 6 + 5},
+
+		// Faces between two of the same transparent block are hidden
+		{"glass row", []VoxelType{7, 7, 7}, 4*3 + 2},
+		{"glass and water", []VoxelType{7, 8}, 6 + 6},
+		{"water, stone, water", []VoxelType{8, stone, 8}, 5 + 6 + 5},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			chunk := testChunk(func(x, y, z int) VoxelType {
+				if y == 0 && z == 0 && x < len(test.row) {
+					return test.row[x]
+				}
+				return air
+			})
+
+			mesh := BuildCulledMesh(chunk.voxelType, blocks, nil)
+			if got := mesh.QuadCount(); got != test.quads {
+				t.Errorf("culled mesh has %d quads, want %d", got, test.quads)
+			}
+		})
+	}
+}
+
+func TestMeshWithoutAtlas(t *testing.T) {
+	blocks := DefaultBlockRegistry()
+	for _, mesh := range []ChunkMesh{BuildCulledMesh(slabLookup, blocks, nil), BuildGreedyMesh(slabLookup, blocks, nil)} {
+		if len(mesh.Texcoords) != 0 || len(mesh.Tiles) != 0 {
+			t.Error("mesh built without an atlas has texture coordinates")
+		}
+	}
+}
+
+func TestGreedyMeshRepeatsTextures(t *testing.T) {
+	blocks := DefaultBlockRegistry()
+	atlas, err := LoadBlockAtlas(blocks, t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mesh := BuildGreedyMesh(slabLookup, blocks, atlas)
+	if len(mesh.Texcoords) != len(mesh.Vertices)/3*2 || len(mesh.Tiles) != len(mesh.Vertices)/3*4 {
+		t.Fatalf("%d texcoords and %d tiles for %d vertices", len(mesh.Texcoords), len(mesh.Tiles), len(mesh.Vertices)/3)
+	}
+
+	for q := range mesh.QuadCount() {
+		// Every vertex of the quad draws from the face's atlas rectangle
+		var want [4]float32
+		switch normal := mesh.Normals[q*12+1]; {
+		case normal > 0:
+			want = atlas.faceUV(stone, faceTop)
+		case normal < 0:
+			want = atlas.faceUV(stone, faceBottom)
+		default:
+			want = atlas.faceUV(stone, faceSide)
+		}
+		for v := q * 4; v < q*4+4; v++ {
+			if got := [4]float32(mesh.Tiles[v*4 : v*4+4]); got != want {
+				t.Fatalf("quad %d vertex %d draws from %v, want %v", q, v, got, want)
+			}
+		}
+
+		// The texture repeats once per voxel, so the coordinates span the
+		// quad's size in voxels
+		lo, hi := quadTexcoords(&mesh, q)
+		if lo != [2]float32{0, 0} {
+			t.Errorf("quad %d texture starts at %v, want 0, 0", q, lo)
+		}
+		size := hi[0] * hi[1]
+		if mesh.Normals[q*12+1] != 0 && (hi != [2]float32{16, 16}) {
+			t.Errorf("top or bottom quad %d spans %v of the texture, want 16 by 16", q, hi)
+		} else if mesh.Normals[q*12+1] == 0 && size != 16 {
+			t.Errorf("side quad %d spans %v of the texture, want 16 by 1", q, hi)
+		}
+	}
+}
+
+func TestMeshSidesAreUpright(t *testing.T) {
+	blocks := DefaultBlockRegistry()
+	atlas, err := LoadBlockAtlas(blocks, t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Image rows go down, so the top of every side face is at t = 0
+	for _, mesh := range []ChunkMesh{BuildCulledMesh(slabLookup, blocks, atlas), BuildGreedyMesh(slabLookup, blocks, atlas)} {
+		for v := range len(mesh.Vertices) / 3 {
+			if mesh.Normals[v*3+1] != 0 {
+				continue
+			}
+			y, tt := mesh.Vertices[v*3+1], mesh.Texcoords[v*2+1]
+			if tt != 1-y {
+				t.Fatalf("side vertex at y = %v has t = %v, want %v", y, tt, 1-y)
+			}
+		}
+	}
+}
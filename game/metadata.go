@@ -0,0 +1,168 @@
+package game
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+const (
+	worldFormatVersion = 1
+
+	worldMetadataFile = "world.json"
+	worldRegionDir    = "regions"
+
+	// worldBlocksFile optionally overrides the built in block definitions,
+	// whose textures are in worldTexturesDir
+	worldBlocksFile  = "blocks.json"
+	worldTexturesDir = "textures"
+)
+
+// WorldMetadata is everything needed to reproduce a world apart from the
+// edited chunks. It's saved as JSON at the root of the world directory
+type WorldMetadata struct {
+	FormatVersion int               `json:"format_version"`
+	Seed          uint64            `json:"seed"`
+	Generator     GeneratorSettings `json:"generator"`
+	Spawn         rl.Vector3        `json:"spawn"`
+
+	// Camera is nil until the world has been played, when it starts at Spawn
+	Camera *CameraState `json:"camera,omitempty"`
+}
+
+// CameraState is where the camera was when the world was last saved
+type CameraState struct {
+	Position rl.Vector3 `json:"position"`
+	Target   rl.Vector3 `json:"target"`
+}
+
+// GeneratorSettings names the terrain generator and overrides its default
+// parameters
+type GeneratorSettings struct {
+	Name   string             `json:"name"`
+	Params map[string]float64 `json:"params,omitempty"`
+}
+
+// param returns the named parameter, or def if it isn't set
+func (s GeneratorSettings) param(name string, def float64) float64 {
+	if v, ok := s.Params[name]; ok {
+		return v
+	}
+	return def
+}
+
+// NewGenerator builds the generator described by the settings
+func (s GeneratorSettings) NewGenerator(seed uint64) (ChunkGenerator, error) {
+	switch s.Name {
+	case "default":
+		pipeline := NewDefaultGenerator(seed)
+
+		terrain := pipeline[0].(*HeightmapGenerator)
+		terrain.BaseHeight = s.param("base_height", terrain.BaseHeight)
+		terrain.Amplitude = s.param("amplitude", terrain.Amplitude)
+		terrain.DirtDepth = int(s.param("dirt_depth", float64(terrain.DirtDepth)))
+
+		caves := pipeline[1].(*CaveCarver)
+		caves.MinDepth = int(s.param("cave_min_depth", float64(caves.MinDepth)))
+
+		return pipeline, nil
+	case "flat":
+		return s.flatGenerator(), nil
+	default:
+		return nil, fmt.Errorf("unknown generator %q", s.Name)
+	}
+}
+
+func (s GeneratorSettings) flatGenerator() FlatGenerator {
+	return FlatGenerator{
+		Height: int(s.param("height", 0)),
+		Type:   VoxelType(s.param("type", float64(grass))),
+	}
+}
+
+// Types returns the voxel types the generator places, which the world's
+// block registry needs to define
+func (s GeneratorSettings) Types() []VoxelType {
+	switch s.Name {
+	case "default":
+		return []VoxelType{grass, dirt, stone}
+	case "flat":
+		return []VoxelType{s.flatGenerator().Type}
+	default:
+		return nil
+	}
+}
+
+// newWorldMetadata creates the metadata for a brand new world with a random
+// seed
+func newWorldMetadata() *WorldMetadata {
+	return &WorldMetadata{
+		FormatVersion: worldFormatVersion,
+		Seed:          rand.Uint64(),
+		Generator:     GeneratorSettings{Name: "default"},
+		Spawn:         rl.NewVector3(0, cameraStartHeight, 0),
+	}
+}
+
+// StartCamera is where the camera was when the world was last saved, or at
+// the spawn point looking ahead and down if it hasn't been played yet
+func (m *WorldMetadata) StartCamera() CameraState {
+	if m.Camera != nil {
+		return *m.Camera
+	}
+
+	return CameraState{
+		Position: m.Spawn,
+		Target:   rl.Vector3Add(m.Spawn, rl.NewVector3(0, -10, 10)),
+	}
+}
+
+// OpenWorldMetadata reads the metadata from the world directory, creating
+// the directory and a new world if it doesn't exist
+func OpenWorldMetadata(dir string) (*WorldMetadata, error) {
+	data, err := os.ReadFile(filepath.Join(dir, worldMetadataFile))
+	if errors.Is(err, os.ErrNotExist) {
+		meta := newWorldMetadata()
+		return meta, meta.Save(dir)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading world metadata: %w", err)
+	}
+
+	meta := &WorldMetadata{}
+	if err := json.Unmarshal(data, meta); err != nil {
+		return nil, fmt.Errorf("parsing world metadata: %w", err)
+	}
+	if meta.FormatVersion != worldFormatVersion {
+		return nil, fmt.Errorf("unsupported world format version %d", meta.FormatVersion)
+	}
+
+	return meta, nil
+}
+
+// Save writes the metadata to the world directory
+func (m *WorldMetadata) Save(dir string) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("creating world directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding world metadata: %w", err)
+	}
+
+	path := filepath.Join(dir, worldMetadataFile)
+	if err := os.WriteFile(path+".tmp", data, 0o644); err != nil {
+		return fmt.Errorf("writing world metadata: %w", err)
+	}
+	if err := os.Rename(path+".tmp", path); err != nil {
+		return fmt.Errorf("writing world metadata: %w", err)
+	}
+
+	return nil
+}
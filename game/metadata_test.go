@@ -0,0 +1,166 @@
+package game
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+func TestWorldMetadataRoundTrip(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "world")
+
+	// Opening a directory that doesn't exist creates a new world
+	created, err := OpenWorldMetadata(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created.FormatVersion != worldFormatVersion || created.Generator.Name != "default" {
+		t.Fatalf("new world has version %d and generator %q", created.FormatVersion, created.Generator.Name)
+	}
+
+	created.Generator.Params = map[string]float64{"amplitude": 12}
+	created.Camera = &CameraState{
+		Position: rl.NewVector3(10, 70, -4),
+		Target:   rl.NewVector3(11, 69, -3),
+	}
+	if err := created.Save(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	opened, err := OpenWorldMetadata(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(opened, created) {
+		t.Errorf("opened %+v, want %+v", opened, created)
+	}
+}
+
+func TestWorldMetadataStartCamera(t *testing.T) {
+	spawn := rl.NewVector3(40, 80, -12)
+	saved := CameraState{
+		Position: rl.NewVector3(1, 2, 3),
+		Target:   rl.NewVector3(4, 5, 6),
+	}
+
+	tests := []struct {
+		name string
+		json string
+		want CameraState
+	}{
+		{
+			name: "never played",
+			json: `{"format_version": 1, "generator": {"name": "flat"}, "spawn": {"X": 40, "Y": 80, "Z": -12}}`,
+			want: CameraState{
+				Position: spawn,
+				Target:   rl.NewVector3(40, 70, -2),
+			},
+		},
+		{
+			name: "played",
+			json: `{"format_version": 1, "generator": {"name": "flat"}, "spawn": {"X": 40, "Y": 80, "Z": -12},
+				"camera": {"position": {"X": 1, "Y": 2, "Z": 3}, "target": {"X": 4, "Y": 5, "Z": 6}}}`,
+			want: saved,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if err := os.WriteFile(filepath.Join(dir, worldMetadataFile), []byte(tt.json), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			meta, err := OpenWorldMetadata(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if meta.Spawn != spawn {
+				t.Errorf("spawn = %v, want %v", meta.Spawn, spawn)
+			}
+			if got := meta.StartCamera(); got != tt.want {
+				t.Errorf("StartCamera() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOpenWorldMetadataRejects(t *testing.T) {
+	tests := []struct {
+		name string
+		json string
+		want string
+	}{
+		{
+			name: "unknown version",
+			json: `{"format_version": 2, "seed": 1, "generator": {"name": "default"}}`,
+			want: "unsupported world format version 2",
+		},
+		{
+			name: "missing version",
+			json: `{"seed": 1, "generator": {"name": "default"}}`,
+			want: "unsupported world format version 0",
+		},
+		{
+			name: "malformed",
+			json: `{"format_version": 1,`,
+			want: "parsing world metadata",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, worldMetadataFile)
+			if err := os.WriteFile(path, []byte(tt.json), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			_, err := OpenWorldMetadata(dir)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("OpenWorldMetadata error = %v, want %q", err, tt.want)
+			}
+
+			// The world isn't overwritten with a new one
+			if data, _ := os.ReadFile(path); string(data) != tt.json {
+				t.Errorf("metadata was rewritten to %s", data)
+			}
+		})
+	}
+}
+
+func TestGeneratorSettings(t *testing.T) {
+	flat, err := GeneratorSettings{
+		Name:   "flat",
+		Params: map[string]float64{"height": 4, "type": float64(dirt)},
+	}.NewGenerator(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (FlatGenerator{Height: 4, Type: dirt}); flat != want {
+		t.Errorf("flat generator is %+v, want %+v", flat, want)
+	}
+
+	generator, err := GeneratorSettings{
+		Name:   "default",
+		Params: map[string]float64{"amplitude": 3, "cave_min_depth": 9},
+	}.NewGenerator(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pipeline := generator.(GenerationPipeline)
+	if got := pipeline[0].(*HeightmapGenerator).Amplitude; got != 3 {
+		t.Errorf("amplitude = %v, want 3", got)
+	}
+	if got := pipeline[1].(*CaveCarver).MinDepth; got != 9 {
+		t.Errorf("cave MinDepth = %d, want 9", got)
+	}
+
+	if _, err := (GeneratorSettings{Name: "islands"}).NewGenerator(1); err == nil {
+		t.Error("unknown generator didn't return an error")
+	}
+}
@@ -0,0 +1,83 @@
+package game
+
+import (
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// Flying speeds in blocks per second
+const (
+	flySpeed       = 10.0
+	flySprintSpeed = 20.0
+)
+
+// moveInput is which movement keys are held down
+type moveInput struct {
+	Forward, Back, Left, Right bool
+	Up, Down                   bool
+	Sprint                     bool
+}
+
+// readMoveInput reads the movement keys
+func readMoveInput() moveInput {
+	return moveInput{
+		Forward: rl.IsKeyDown(rl.KeyW),
+		Back:    rl.IsKeyDown(rl.KeyS),
+		Left:    rl.IsKeyDown(rl.KeyA),
+		Right:   rl.IsKeyDown(rl.KeyD),
+		Up:      rl.IsKeyDown(rl.KeySpace),
+		Down:    rl.IsKeyDown(rl.KeyLeftAlt),
+		Sprint:  rl.IsKeyDown(rl.KeyLeftShift),
+	}
+}
+
+// moveDirection returns the unit direction the keys point in, relative to
+// forward and up. It's zero when no keys are held or they cancel out
+func moveDirection(forward, up rl.Vector3, in moveInput, vertical bool) rl.Vector3 {
+	forward = rl.Vector3Normalize(forward)
+	right := rl.Vector3Normalize(rl.Vector3CrossProduct(forward, up))
+
+	var direction rl.Vector3
+	if in.Forward {
+		direction = rl.Vector3Add(direction, forward)
+	}
+	if in.Back {
+		direction = rl.Vector3Subtract(direction, forward)
+	}
+	if in.Right {
+		direction = rl.Vector3Add(direction, right)
+	}
+	if in.Left {
+		direction = rl.Vector3Subtract(direction, right)
+	}
+	if vertical && in.Up {
+		direction = rl.Vector3Add(direction, up)
+	}
+	if vertical && in.Down {
+		direction = rl.Vector3Subtract(direction, up)
+	}
+
+	// Normalizing leaves the zero vector alone
+	return rl.Vector3Normalize(direction)
+}
+
+// flyDisplacement returns how far the camera flies in dt seconds while
+// looking along look. The distance only depends on dt, not on how far the
+// camera's target is or how many frames dt is split over
+func flyDisplacement(look, up rl.Vector3, in moveInput, dt float32) rl.Vector3 {
+	speed := float32(flySpeed)
+	if in.Sprint {
+		speed = flySprintSpeed
+	}
+	return rl.Vector3Scale(moveDirection(look, up, in, true), speed*dt)
+}
+
+// walkVelocity returns the horizontal velocity of a player looking along
+// look, in blocks per second. Looking up or down doesn't slow them down
+func walkVelocity(look, up rl.Vector3, in moveInput) rl.Vector3 {
+	look.Y = 0
+	speed := float32(playerWalkSpeed)
+	if in.Sprint {
+		speed = playerSprintSpeed
+	}
+	return rl.Vector3Scale(moveDirection(look, up, in, false), speed)
+}
@@ -0,0 +1,91 @@
+package game
+
+import (
+	"testing"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+func TestFlyDisplacementFrameRateIndependent(t *testing.T) {
+	look := rl.NewVector3(0.3, -0.4, 2)
+	inputs := map[string]moveInput{
+		"forward":        {Forward: true},
+		"strafe":         {Right: true},
+		"diagonal":       {Forward: true, Left: true, Up: true},
+		"sprinting back": {Back: true, Sprint: true},
+	}
+
+	for name, in := range inputs {
+		t.Run(name, func(t *testing.T) {
+			// One second split into frames of each length
+			rates := []int{30, 60, 144}
+			var totals []rl.Vector3
+			for _, frames := range rates {
+				var total rl.Vector3
+				for range frames {
+					total = rl.Vector3Add(total, flyDisplacement(look, worldUp, in, 1/float32(frames)))
+				}
+				totals = append(totals, total)
+			}
+
+			speed := float32(flySpeed)
+			if in.Sprint {
+				speed = flySprintSpeed
+			}
+			for i, total := range totals {
+				if d := rl.Vector3Length(total); d < speed-1e-3 || d > speed+1e-3 {
+					t.Errorf("flew %v in a second at %d fps, want %v", d, rates[i], speed)
+				}
+				if d := rl.Vector3Distance(total, totals[0]); d > 1e-3 {
+					t.Errorf("flew to %v at %d fps but %v at 30 fps", total, rates[i], totals[0])
+				}
+			}
+		})
+	}
+}
+
+func TestFlyDisplacementIgnoresLookLength(t *testing.T) {
+	in := moveInput{Forward: true, Right: true}
+	short := flyDisplacement(rl.NewVector3(0, 0, 0.01), worldUp, in, tickDuration)
+	long := flyDisplacement(rl.NewVector3(0, 0, 500), worldUp, in, tickDuration)
+	if rl.Vector3Distance(short, long) > 1e-6 {
+		t.Errorf("flew %v looking at a near target but %v at a far one", short, long)
+	}
+}
+
+func TestMoveDirection(t *testing.T) {
+	forward := rl.NewVector3(0, 0, 1)
+	tests := []struct {
+		name     string
+		in       moveInput
+		vertical bool
+		want     rl.Vector3
+	}{
+		{"nothing", moveInput{}, true, rl.Vector3{}},
+		{"forward", moveInput{Forward: true}, false, rl.NewVector3(0, 0, 1)},
+		{"cancelled", moveInput{Forward: true, Back: true, Left: true, Right: true}, true, rl.Vector3{}},
+		{"right", moveInput{Right: true}, false, rl.NewVector3(-1, 0, 0)},
+		{"up flying", moveInput{Up: true}, true, rl.NewVector3(0, 1, 0)},
+		{"up walking", moveInput{Up: true}, false, rl.Vector3{}},
+		{"diagonal", moveInput{Forward: true, Left: true}, false, rl.NewVector3(0.70710677, 0, 0.70710677)},
+	}
+	for _, test := range tests {
+		if got := moveDirection(forward, worldUp, test.in, test.vertical); rl.Vector3Distance(got, test.want) > 1e-6 {
+			t.Errorf("%s: moveDirection = %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestWalkVelocityIsHorizontal(t *testing.T) {
+	// Looking steeply down still walks at full speed along the ground
+	for _, look := range []rl.Vector3{rl.NewVector3(0, -5, 1), rl.NewVector3(1, 0, 1), rl.NewVector3(-2, 3, 0)} {
+		v := walkVelocity(look, worldUp, moveInput{Forward: true})
+		if v.Y != 0 || rl.Vector3Length(v) < playerWalkSpeed-1e-4 || rl.Vector3Length(v) > playerWalkSpeed+1e-4 {
+			t.Errorf("walking looking along %v = %v, want horizontal at %v", look, v, playerWalkSpeed)
+		}
+	}
+
+	if v := walkVelocity(rl.NewVector3(0, 0, 1), worldUp, moveInput{Forward: true, Sprint: true}); v.Z != playerSprintSpeed {
+		t.Errorf("sprinting = %v, want %v forward", v, playerSprintSpeed)
+	}
+}
@@ -1,11 +1,11 @@
 package game
 
 import (
-// rl "github.com/gen2brain/raylib-go/raylib"
-// "math"
+	"math"
+	"math/rand/v2"
 )
 
-// Permutation table
+// Reference permutation table which is shuffled per seed
 var permutation = []int{
 	151, 160, 137, 91, 90, 15, 131, 13, 201, 95, 96, 53, 194, 233, 7, 225, 140, 36, 103, 30, 69, 142,
 	8, 99, 37, 240, 21, 10, 23, 190, 6, 148, 247, 120, 234, 75, 0, 26, 197, 62, 94, 252, 219, 203, 117,
@@ -21,17 +21,53 @@ var permutation = []int{
 	138, 236, 205, 93, 222, 114, 67, 29, 24, 72, 243, 141, 128, 195, 78, 66, 215, 61, 156, 180,
 }
 
-// Noise returns a Perlin noise value for the given coordinates
-func Noise(x, y, z uint8) float32 {
-	// Use integer coordinates directly since input is already uint8
-	xi := int(x) & 255
-	yi := int(y) & 255
-	zi := int(z) & 255
+// NoiseSource is a deterministic noise function of a point in space. Values
+// are in the range [-1, 1]
+type NoiseSource interface {
+	Noise(x, y, z float64) float64
+}
+
+// Perlin is a 3D gradient noise generator. Each seed shuffles the
+// permutation table differently so worlds are reproducible per seed
+type Perlin struct {
+	Seed uint64
+
+	// permutation table repeated twice so lookups can't overflow
+	perm [512]int
+}
+
+// Create a new Perlin noise generator for the seed
+func NewPerlin(seed uint64) *Perlin {
+	p := &Perlin{Seed: seed}
+
+	shuffled := make([]int, len(permutation))
+	copy(shuffled, permutation)
+
+	rng := rand.New(rand.NewPCG(seed, seed^0x9e3779b97f4a7c15))
+	rng.Shuffle(len(shuffled), func(i, j int) {
+		shuffled[i], shuffled[j] = shuffled[j], shuffled[i]
+	})
 
-	// Convert to normalized coordinates in [0,1]
-	xf := float32(x % 1)
-	yf := float32(y % 1)
-	zf := float32(z % 1)
+	for i := range p.perm {
+		p.perm[i] = shuffled[i&255]
+	}
+
+	return p
+}
+
+// Noise returns the Perlin noise value for the given coordinates in the
+// range [-1, 1]. Integer coordinates always return 0
+func (p *Perlin) Noise(x, y, z float64) float64 {
+	// Unit cube that contains the point
+	xFloor, yFloor, zFloor := math.Floor(x), math.Floor(y), math.Floor(z)
+	xi := int(xFloor) & 255
+	yi := int(yFloor) & 255
+	zi := int(zFloor) & 255
+
+	// Relative position of the point in the cube in [0,1]
+	xf := x - xFloor
+	yf := y - yFloor
+	zf := z - zFloor
 
 	// Compute fade curves
 	u := fade(xf)
@@ -39,14 +75,15 @@ func Noise(x, y, z uint8) float32 {
 	w := fade(zf)
 
 	// Hash coordinates of cube corners
-	aaa := p(p(p(xi)+yi) + zi)
-	aba := p(p(p(xi)+yi+1) + zi)
-	aab := p(p(p(xi)+yi) + zi + 1)
-	abb := p(p(p(xi)+yi+1) + zi + 1)
-	baa := p(p(p(xi+1)+yi) + zi)
-	bba := p(p(p(xi+1)+yi+1) + zi)
-	bab := p(p(p(xi+1)+yi) + zi + 1)
-	bbb := p(p(p(xi+1)+yi+1) + zi + 1)
+	perm := &p.perm
+	aaa := perm[perm[perm[xi]+yi]+zi]
+	aba := perm[perm[perm[xi]+yi+1]+zi]
+	aab := perm[perm[perm[xi]+yi]+zi+1]
+	abb := perm[perm[perm[xi]+yi+1]+zi+1]
+	baa := perm[perm[perm[xi+1]+yi]+zi]
+	bba := perm[perm[perm[xi+1]+yi+1]+zi]
+	bab := perm[perm[perm[xi+1]+yi]+zi+1]
+	bbb := perm[perm[perm[xi+1]+yi+1]+zi+1]
 
 	// Blend gradients using trilinear interpolation
 	x1 := lerp(
@@ -73,23 +110,19 @@ func Noise(x, y, z uint8) float32 {
 	return lerp(y1, y2, w)
 }
 
-func p(i int) int {
-	return permutation[i&255]
-}
-
-func fade(t float32) float32 {
+func fade(t float64) float64 {
 	return t * t * t * (t*(t*6-15) + 10)
 }
 
 // aka linear interpolation
-func lerp(a, b, t float32) float32 {
+func lerp(a, b, t float64) float64 {
 	return a + t*(b-a)
 }
 
-func grad(hash int, x, y, z float32) float32 {
+func grad(hash int, x, y, z float64) float64 {
 	h := hash & 15
-	u := float32(0)
-	v := float32(0)
+	u := float64(0)
+	v := float64(0)
 
 	switch h < 8 {
 	case true:
@@ -109,7 +142,7 @@ func grad(hash int, x, y, z float32) float32 {
 		}
 	}
 
-	var result float32
+	var result float64
 	switch (h & 1) == 0 {
 	case true:
 		result = u
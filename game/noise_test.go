@@ -0,0 +1,150 @@
+package game
+
+import (
+	"math"
+	"testing"
+)
+
+// samplePoints walks a lattice of non integer points, calling fn for each
+func samplePoints(n int, fn func(x, y, z float64)) {
+	for i := range n {
+		for j := range n {
+			for k := range n {
+				fn(float64(i)*0.37-11.3, float64(j)*0.41+3.7, float64(k)*0.53-2.9)
+			}
+		}
+	}
+}
+
+func TestPerlinRange(t *testing.T) {
+	p := NewPerlin(42)
+	var low, high float64
+	samplePoints(40, func(x, y, z float64) {
+		n := p.Noise(x, y, z)
+		if n < -1 || n > 1 || math.IsNaN(n) {
+			t.Fatalf("Noise(%v, %v, %v) = %v, want in [-1, 1]", x, y, z, n)
+		}
+		low, high = min(low, n), max(high, n)
+	})
+
+	// The samples should use a good part of the range, not sit near 0
+	if low > -0.4 || high < 0.4 {
+		t.Errorf("noise only spans [%v, %v]", low, high)
+	}
+}
+
+func TestPerlinIntegerPointsAreZero(t *testing.T) {
+	p := NewPerlin(7)
+	for _, c := range [][3]float64{{0, 0, 0}, {1, 2, 3}, {-5, 17, -300}, {255, 256, 257}} {
+		if n := p.Noise(c[0], c[1], c[2]); n != 0 {
+			t.Errorf("Noise(%v) = %v, want 0", c, n)
+		}
+	}
+}
+
+func TestPerlinContinuity(t *testing.T) {
+	p := NewPerlin(1)
+	const step = 1e-4
+
+	// The gradients are bounded so a tiny step can only change the value a
+	// little, including across the faces of the unit cubes
+	samplePoints(20, func(x, y, z float64) {
+		n := p.Noise(x, y, z)
+		for _, d := range [][3]float64{{step, 0, 0}, {0, step, 0}, {0, 0, step}} {
+			if diff := math.Abs(p.Noise(x+d[0], y+d[1], z+d[2]) - n); diff > 10*step {
+				t.Fatalf("Noise jumps by %v stepping %v from (%v, %v, %v)", diff, d, x, y, z)
+			}
+		}
+	})
+
+	for _, x := range []float64{-1, 0, 1, 2, 3} {
+		below, above := p.Noise(x-step, 0.5, 0.5), p.Noise(x+step, 0.5, 0.5)
+		if diff := math.Abs(above - below); diff > 10*step {
+			t.Errorf("Noise jumps by %v across x = %v", diff, x)
+		}
+	}
+}
+
+func TestPerlinDeterministicPerSeed(t *testing.T) {
+	a, b, other := NewPerlin(1234), NewPerlin(1234), NewPerlin(1235)
+
+	var differs bool
+	samplePoints(10, func(x, y, z float64) {
+		if a.Noise(x, y, z) != b.Noise(x, y, z) {
+			t.Fatalf("same seed gave different noise at (%v, %v, %v)", x, y, z)
+		}
+		if a.Noise(x, y, z) != other.Noise(x, y, z) {
+			differs = true
+		}
+	})
+	if !differs {
+		t.Error("different seeds gave identical noise")
+	}
+}
+
+func BenchmarkPerlin(b *testing.B) {
+	p := NewPerlin(1)
+	for i := range b.N {
+		p.Noise(float64(i)*0.01, 0.5, float64(i)*0.007)
+	}
+}
+
+// fractals returns each fractal over the same Perlin source
+func fractals(seed uint64) map[string]NoiseSource {
+	source := NewPerlin(seed)
+	fractal := DefaultFractal(5)
+	return map[string]NoiseSource{
+		"FBM":    FBM{Source: source, Fractal: fractal},
+		"Billow": Billow{Source: source, Fractal: fractal},
+		"Ridged": Ridged{Source: source, Fractal: fractal, Gain: 2},
+	}
+}
+
+func TestFractalRangeAndDeterminism(t *testing.T) {
+	a, b := fractals(99), fractals(99)
+	for name, noise := range a {
+		t.Run(name, func(t *testing.T) {
+			samplePoints(16, func(x, y, z float64) {
+				n := noise.Noise(x, y, z)
+				if n < -1 || n > 1 || math.IsNaN(n) {
+					t.Fatalf("Noise(%v, %v, %v) = %v, want in [-1, 1]", x, y, z, n)
+				}
+				if again := b[name].Noise(x, y, z); again != n {
+					t.Fatalf("same seed gave %v then %v at (%v, %v, %v)", n, again, x, y, z)
+				}
+			})
+		})
+	}
+}
+
+func TestFractalNoOctaves(t *testing.T) {
+	for name, noise := range map[string]NoiseSource{
+		"FBM":    FBM{Source: NewPerlin(1)},
+		"Billow": Billow{Source: NewPerlin(1)},
+		"Ridged": Ridged{Source: NewPerlin(1)},
+	} {
+		if n := noise.Noise(0.3, 0.6, 0.9); n != 0 {
+			t.Errorf("%s with no octaves = %v, want 0", name, n)
+		}
+	}
+}
+
+func TestFBMSingleOctaveIsSource(t *testing.T) {
+	source := NewPerlin(5)
+	fbm := FBM{Source: source, Fractal: DefaultFractal(1)}
+	samplePoints(8, func(x, y, z float64) {
+		if got, want := fbm.Noise(x, y, z), source.Noise(x, y, z); got != want {
+			t.Fatalf("FBM(%v, %v, %v) = %v, want %v", x, y, z, got, want)
+		}
+	})
+}
+
+func benchmarkNoise(b *testing.B, noise NoiseSource) {
+	for i := range b.N {
+		noise.Noise(float64(i)*0.01, 0.5, float64(i)*0.007)
+	}
+}
+
+func BenchmarkFBM(b *testing.B)    { benchmarkNoise(b, fractals(1)["FBM"]) }
+func BenchmarkBillow(b *testing.B) { benchmarkNoise(b, fractals(1)["Billow"]) }
+func BenchmarkRidged(b *testing.B) { benchmarkNoise(b, fractals(1)["Ridged"]) }
@@ -0,0 +1,150 @@
+package game
+
+import (
+	"slices"
+)
+
+// paletteWidths are the index sizes in bits that storage grows through.
+// They all divide 64 so an index never straddles two words
+var paletteWidths = []uint8{1, 2, 4, 8, 16}
+
+// paletteStorage holds a chunk's voxels as bit packed indices into a palette
+// of the types used in it. The indices widen automatically as types are
+// added, and while the whole chunk is a single type, like all air or all
+// stone, no indices are stored at all
+type paletteStorage struct {
+	palette []VoxelType
+
+	// counts is how many voxels use each palette entry, so entries nothing
+	// uses any more can be reused and a chunk that's become a single type
+	// can drop its indices
+	counts []int
+
+	// bits per index, 0 while the whole chunk is palette[0] (or air if the
+	// palette is empty)
+	bits uint8
+	data []uint64
+}
+
+func (s *paletteStorage) get(i int) VoxelType {
+	if len(s.palette) == 0 {
+		return air
+	}
+	return s.palette[s.index(i)]
+}
+
+// index returns the palette index stored for voxel i
+func (s *paletteStorage) index(i int) int {
+	if s.bits == 0 {
+		return 0
+	}
+
+	perWord := 64 / int(s.bits)
+	shift := uint(i%perWord) * uint(s.bits)
+	mask := uint64(1)<<s.bits - 1
+
+	return int((s.data[i/perWord] >> shift) & mask)
+}
+
+func (s *paletteStorage) set(i int, t VoxelType) {
+	// An empty palette is all air, which doesn't need storing
+	if len(s.palette) == 0 {
+		if t == air {
+			return
+		}
+		s.palette, s.counts = []VoxelType{air}, []int{chunkVolume}
+	}
+
+	old := s.index(i)
+	if s.palette[old] == t {
+		return
+	}
+
+	// Releasing the old entry first lets t take it over if nothing else
+	// uses it
+	s.counts[old]--
+
+	index := s.paletteIndex(t)
+	if index < 0 {
+		index = slices.Index(s.counts, 0)
+		if index >= 0 {
+			s.palette[index] = t
+		} else {
+			s.palette = append(s.palette, t)
+			s.counts = append(s.counts, 0)
+			index = len(s.palette) - 1
+
+			if len(s.palette) > 1<<s.bits {
+				s.grow()
+			}
+		}
+	}
+
+	s.counts[index]++
+
+	// The whole chunk is one type again, so the indices aren't needed
+	if s.counts[index] == chunkVolume {
+		*s = paletteStorage{palette: []VoxelType{t}, counts: []int{chunkVolume}}
+		return
+	}
+
+	perWord := 64 / int(s.bits)
+	shift := uint(i%perWord) * uint(s.bits)
+	mask := uint64(1)<<s.bits - 1
+
+	word := &s.data[i/perWord]
+	*word = *word&^(mask<<shift) | uint64(index)<<shift
+}
+
+// recount rebuilds counts from the stored indices, which must all be in
+// the palette
+func (s *paletteStorage) recount() {
+	if len(s.palette) == 0 {
+		s.counts = nil
+		return
+	}
+
+	s.counts = make([]int, len(s.palette))
+	for i := range chunkVolume {
+		s.counts[s.index(i)]++
+	}
+}
+
+// paletteIndex returns the index of the type in the palette, or -1
+func (s *paletteStorage) paletteIndex(t VoxelType) int {
+	for i, pt := range s.palette {
+		if pt == t {
+			return i
+		}
+	}
+	return -1
+}
+
+// grow repacks the indices at the next width that fits the palette
+func (s *paletteStorage) grow() {
+	bits := s.bits
+	for _, width := range paletteWidths {
+		if 1<<width >= len(s.palette) {
+			bits = width
+			break
+		}
+	}
+
+	grown := paletteStorage{
+		palette: s.palette,
+		counts:  s.counts,
+		bits:    bits,
+		data:    make([]uint64, chunkVolume*int(bits)/64),
+	}
+
+	// Every index is 0 when growing from a single value, which the zeroed
+	// data already holds
+	if s.bits != 0 {
+		perWord := 64 / int(bits)
+		for i := range chunkVolume {
+			grown.data[i/perWord] |= uint64(s.index(i)) << (uint(i%perWord) * uint(bits))
+		}
+	}
+
+	*s = grown
+}
@@ -0,0 +1,106 @@
+package game
+
+import (
+	"math/rand/v2"
+	"testing"
+)
+
+// checkPalette compares every voxel in the storage against want
+func checkPalette(t *testing.T, s *paletteStorage, want *[chunkVolume]VoxelType) {
+	t.Helper()
+	for i, w := range want {
+		if got := s.get(i); got != w {
+			t.Fatalf("get(%d) = %v, want %v", i, got, w)
+		}
+	}
+}
+
+func TestPaletteStorageRandom(t *testing.T) {
+	rng := rand.New(rand.NewPCG(1, 2))
+	var s paletteStorage
+	var want [chunkVolume]VoxelType
+
+	// Widen through every width by using more and more types
+	for _, types := range []int{2, 3, 5, 17, 300} {
+		for range 5000 {
+			i, v := rng.IntN(chunkVolume), VoxelType(rng.IntN(types))
+			s.set(i, v)
+			want[i] = v
+		}
+		checkPalette(t, &s, &want)
+	}
+	if s.bits != 16 {
+		t.Errorf("bits = %d after 300 types, want 16", s.bits)
+	}
+}
+
+func TestPaletteStorageSingleValue(t *testing.T) {
+	var s paletteStorage
+	if s.get(100) != air || s.data != nil {
+		t.Fatal("empty storage isn't all air")
+	}
+
+	// Filling a chunk with stone one voxel at a time ends with no indices
+	for i := range chunkVolume {
+		s.set(i, stone)
+	}
+	if s.bits != 0 || s.data != nil || len(s.palette) != 1 || s.palette[0] != stone {
+		t.Fatalf("all stone storage has palette %v at %d bits", s.palette, s.bits)
+	}
+	if s.get(0) != stone || s.get(chunkVolume-1) != stone {
+		t.Fatal("all stone storage doesn't read back stone")
+	}
+
+	// Back to mixed, then all air
+	s.set(5, dirt)
+	if s.bits != 1 || s.get(5) != dirt || s.get(6) != stone {
+		t.Fatalf("mixed storage has palette %v at %d bits", s.palette, s.bits)
+	}
+	for i := range chunkVolume {
+		s.set(i, air)
+	}
+	if s.bits != 0 || s.data != nil || s.get(5) != air {
+		t.Fatalf("all air storage has palette %v at %d bits", s.palette, s.bits)
+	}
+}
+
+func TestPaletteStorageReusesEntries(t *testing.T) {
+	var s paletteStorage
+	s.set(0, grass)
+	s.set(1, dirt)
+	s.set(2, stone)
+	if s.bits != 2 {
+		t.Fatalf("bits = %d with 4 types, want 2", s.bits)
+	}
+
+	// Replacing a type nothing else uses takes its palette entry rather
+	// than widening
+	for range 20 {
+		s.set(0, air)
+		s.set(0, grass)
+	}
+	for v := VoxelType(10); v < 20; v++ {
+		s.set(1, v)
+	}
+	if s.bits != 2 || len(s.palette) != 4 {
+		t.Errorf("palette %v at %d bits after replacing types, want 4 entries at 2 bits", s.palette, s.bits)
+	}
+	if s.get(0) != grass || s.get(1) != 19 || s.get(2) != stone || s.get(3) != air {
+		t.Errorf("voxels are %v %v %v %v", s.get(0), s.get(1), s.get(2), s.get(3))
+	}
+}
+
+func TestPaletteStorageRecount(t *testing.T) {
+	var s paletteStorage
+	for i := range 100 {
+		s.set(i, VoxelType(i%3+1))
+	}
+
+	want := append([]int(nil), s.counts...)
+	s.recount()
+	for i := range want {
+		if s.counts[i] != want[i] {
+			t.Fatalf("recount gave %v, want %v", s.counts, want)
+		}
+	}
+}
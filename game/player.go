@@ -0,0 +1,197 @@
+package game
+
+import (
+	"math"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// Player dimensions and movement, in blocks and seconds
+const (
+	playerWidth      = 0.6
+	playerHeight     = 1.8
+	playerEyeHeight  = 1.62
+	playerStepHeight = 1.0
+
+	playerWalkSpeed   = 4.3
+	playerSprintSpeed = 5.6
+	playerJumpSpeed   = 8.4
+
+	gravity          = 28.0
+	terminalVelocity = 60.0
+
+	// collisionEpsilon keeps faces that only touch from counting as
+	// overlapping
+	collisionEpsilon = 1e-6
+)
+
+// Player is a box that walks on the world and collides with solid voxels
+type Player struct {
+	// Position is the center of the bottom of the box
+	Position rl.Vector3
+	Velocity rl.Vector3
+
+	// OnGround is set when the last Step ended standing on a solid voxel
+	OnGround bool
+}
+
+// Create a new player standing at the position
+func NewPlayer(position rl.Vector3) *Player {
+	return &Player{Position: position}
+}
+
+// Eye returns where the camera goes when looking through the player's eyes
+func (p *Player) Eye() rl.Vector3 {
+	return rl.NewVector3(p.Position.X, p.Position.Y+playerEyeHeight, p.Position.Z)
+}
+
+// Occupies reports whether the player's box overlaps the voxel, so it can't
+// be placed there
+func (p *Player) Occupies(pos BlockPos) bool {
+	box := p.box()
+	for axis, n := range [3]int{pos.X, pos.Y, pos.Z} {
+		lo, hi := box.voxelRange(axis)
+		if n < lo || n > hi {
+			return false
+		}
+	}
+	return true
+}
+
+// SolidFunc reports whether the voxel at the position blocks movement
+type SolidFunc func(pos BlockPos) bool
+
+// Step advances the player by dt seconds. walk is the horizontal velocity
+// wanted in blocks per second and jump launches the player if they're on
+// the ground. The box is moved one axis at a time, stopping against solid
+// voxels, and steps up onto ledges no higher than playerStepHeight
+func (p *Player) Step(walk rl.Vector3, jump bool, dt float32, solid SolidFunc) {
+	p.Velocity.X, p.Velocity.Z = walk.X, walk.Z
+	if jump && p.OnGround {
+		p.Velocity.Y = playerJumpSpeed
+	}
+	p.Velocity.Y = max(p.Velocity.Y-gravity*dt, -terminalVelocity)
+
+	box := p.box()
+	delta := [3]float64{
+		float64(p.Velocity.X * dt),
+		float64(p.Velocity.Y * dt),
+		float64(p.Velocity.Z * dt),
+	}
+
+	// Vertical first so the horizontal moves know whether the player is
+	// standing on something
+	moved := box.sweep(1, delta[1], solid)
+	box = box.offset(1, moved)
+	p.OnGround = delta[1] < 0 && moved > delta[1]
+	if moved != delta[1] {
+		p.Velocity.Y = 0
+	}
+
+	for _, axis := range []int{0, 2} {
+		moved := box.sweep(axis, delta[axis], solid)
+		if moved != delta[axis] && p.OnGround {
+			if stepped, ok := box.stepUp(axis, delta[axis], moved, solid); ok {
+				box = stepped
+				continue
+			}
+		}
+		box = box.offset(axis, moved)
+	}
+
+	p.Position = rl.NewVector3(
+		float32((box.min[0]+box.max[0])/2),
+		float32(box.min[1]),
+		float32((box.min[2]+box.max[2])/2),
+	)
+}
+
+// box returns the player's collision box
+func (p *Player) box() aabb {
+	x, y, z := float64(p.Position.X), float64(p.Position.Y), float64(p.Position.Z)
+	return aabb{
+		min: [3]float64{x - playerWidth/2, y, z - playerWidth/2},
+		max: [3]float64{x + playerWidth/2, y + playerHeight, z + playerWidth/2},
+	}
+}
+
+// aabb is an axis aligned box in world space
+type aabb struct {
+	min, max [3]float64
+}
+
+// offset returns the box moved by delta along the axis
+func (b aabb) offset(axis int, delta float64) aabb {
+	b.min[axis] += delta
+	b.max[axis] += delta
+	return b
+}
+
+// sweep returns how far the box can move towards delta along the axis
+// before running into a solid voxel. Every layer of voxels passed through
+// is checked so fast moves can't tunnel through thin walls
+func (b aabb) sweep(axis int, delta float64, solid SolidFunc) float64 {
+	if delta == 0 {
+		return 0
+	}
+
+	// Voxels are centered on their integer position, so layer n spans from
+	// n-0.5 to n+0.5
+	if delta > 0 {
+		face := b.max[axis]
+		for layer := int(math.Ceil(face + 0.5 - collisionEpsilon)); float64(layer)-0.5 < face+delta; layer++ {
+			if b.layerSolid(axis, layer, solid) {
+				return float64(layer) - 0.5 - face
+			}
+		}
+	} else {
+		face := b.min[axis]
+		for layer := int(math.Floor(face - 0.5 + collisionEpsilon)); float64(layer)+0.5 > face+delta; layer-- {
+			if b.layerSolid(axis, layer, solid) {
+				return float64(layer) + 0.5 - face
+			}
+		}
+	}
+	return delta
+}
+
+// layerSolid reports whether any voxel in the layer along the axis that
+// the box overlaps on the other two axes is solid
+func (b aabb) layerSolid(axis, layer int, solid SolidFunc) bool {
+	u, v := (axis+1)%3, (axis+2)%3
+	uMin, uMax := b.voxelRange(u)
+	vMin, vMax := b.voxelRange(v)
+
+	var pos [3]int
+	pos[axis] = layer
+	for pos[u] = uMin; pos[u] <= uMax; pos[u]++ {
+		for pos[v] = vMin; pos[v] <= vMax; pos[v]++ {
+			if solid(BlockPos{X: pos[0], Y: pos[1], Z: pos[2]}) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// voxelRange returns the first and last voxel the box overlaps on the axis
+func (b aabb) voxelRange(axis int) (int, int) {
+	return int(math.Floor(b.min[axis] + collisionEpsilon + 0.5)),
+		int(math.Floor(b.max[axis] - collisionEpsilon + 0.5))
+}
+
+// stepUp tries the blocked horizontal move again from up to
+// playerStepHeight higher, settling back down onto whatever is there. It
+// only succeeds if that gets further than the blocked move did
+func (b aabb) stepUp(axis int, delta, blocked float64, solid SolidFunc) (aabb, bool) {
+	rise := b.sweep(1, playerStepHeight, solid)
+	raised := b.offset(1, rise)
+
+	moved := raised.sweep(axis, delta, solid)
+	if math.Abs(moved) <= math.Abs(blocked) {
+		return b, false
+	}
+	raised = raised.offset(axis, moved)
+
+	return raised.offset(1, raised.sweep(1, -rise, solid)), true
+}
@@ -0,0 +1,192 @@
+package game
+
+import (
+	"math"
+	"testing"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// solidFloor is solid at and below y = 0, so its top is at y = 0.5
+func solidFloor(p BlockPos) bool {
+	return p.Y <= 0
+}
+
+// stepPlayer steps the player for n ticks
+func stepPlayer(p *Player, n int, walk rl.Vector3, jump bool, solid SolidFunc) {
+	for range n {
+		p.Step(walk, jump, tickDuration, solid)
+	}
+}
+
+func TestPlayerLands(t *testing.T) {
+	p := NewPlayer(rl.NewVector3(0.3, 5, -0.2))
+
+	stepPlayer(p, 60, rl.Vector3{}, false, solidFloor)
+	if !p.OnGround || p.Position.Y != 0.5 || p.Velocity.Y != 0 {
+		t.Fatalf("after falling, player is at %v moving %v, on ground %v, want standing at y = 0.5", p.Position, p.Velocity, p.OnGround)
+	}
+	if p.Position.X != 0.3 || p.Position.Z != -0.2 {
+		t.Errorf("player drifted to %v while falling straight down", p.Position)
+	}
+
+	// Stays put standing still
+	stepPlayer(p, 10, rl.Vector3{}, false, solidFloor)
+	if !p.OnGround || p.Position.Y != 0.5 {
+		t.Errorf("standing player moved to %v", p.Position)
+	}
+}
+
+func TestPlayerJumps(t *testing.T) {
+	p := NewPlayer(rl.NewVector3(0, 0.5, 0))
+	p.Step(rl.Vector3{}, false, tickDuration, solidFloor)
+
+	p.Step(rl.Vector3{}, true, tickDuration, solidFloor)
+	if p.OnGround || p.Position.Y <= 0.5 {
+		t.Fatalf("jumping player is at %v, on ground %v", p.Position, p.OnGround)
+	}
+
+	// Jump height is v²/2g, a little over a block
+	var peak float32
+	for range 60 {
+		p.Step(rl.Vector3{}, true, tickDuration, solidFloor)
+		peak = max(peak, p.Position.Y)
+		if p.OnGround {
+			break
+		}
+	}
+	if want := 0.5 + playerJumpSpeed*playerJumpSpeed/(2*gravity); math.Abs(float64(peak)-want) > 0.2 {
+		t.Errorf("jump peaked at %v, want about %v", peak, want)
+	}
+	if !p.OnGround || p.Position.Y != 0.5 {
+		t.Errorf("player didn't land, at %v", p.Position)
+	}
+
+	// Can't jump again in mid air
+	p.Position.Y = 10
+	p.OnGround = false
+	p.Velocity.Y = 0
+	p.Step(rl.Vector3{}, true, tickDuration, solidFloor)
+	if p.Velocity.Y > 0 {
+		t.Error("player jumped in mid air")
+	}
+}
+
+func TestPlayerStopsAtWalls(t *testing.T) {
+	// A wall three blocks high on x = 3 and a corner on z = -3
+	solid := func(p BlockPos) bool {
+		return solidFloor(p) || (p.Y <= 3 && (p.X == 3 || p.Z == -3))
+	}
+
+	tests := []struct {
+		walk rl.Vector3
+		want rl.Vector3
+	}{
+		{rl.NewVector3(playerWalkSpeed, 0, 0), rl.NewVector3(3-0.5-playerWidth/2, 0.5, 0)},
+		{rl.NewVector3(0, 0, -playerWalkSpeed), rl.NewVector3(0, 0.5, -3+0.5+playerWidth/2)},
+
+		// Sliding along the wall into the corner
+		{rl.NewVector3(playerWalkSpeed, 0, -playerWalkSpeed), rl.NewVector3(3-0.5-playerWidth/2, 0.5, -3+0.5+playerWidth/2)},
+	}
+	for _, test := range tests {
+		p := NewPlayer(rl.NewVector3(0, 0.5, 0))
+		stepPlayer(p, 120, test.walk, false, solid)
+
+		if d := rl.Vector3Distance(p.Position, test.want); d > 1e-5 || !p.OnGround {
+			t.Errorf("walking %v ended at %v, on ground %v, want %v", test.walk, p.Position, p.OnGround, test.want)
+		}
+	}
+}
+
+func TestPlayerStepsUp(t *testing.T) {
+	// A ledge one block high from x = 3 on
+	ledge := func(p BlockPos) bool {
+		return solidFloor(p) || (p.X >= 3 && p.Y == 1)
+	}
+
+	p := NewPlayer(rl.NewVector3(0, 0.5, 0))
+	stepPlayer(p, 120, rl.NewVector3(playerWalkSpeed, 0, 0), false, ledge)
+	if !p.OnGround || p.Position.Y != 1.5 || p.Position.X < 4 {
+		t.Errorf("walking onto a one block ledge ended at %v, on ground %v", p.Position, p.OnGround)
+	}
+
+	// Walking back off drops down again
+	stepPlayer(p, 120, rl.NewVector3(-playerWalkSpeed, 0, 0), false, ledge)
+	if !p.OnGround || p.Position.Y != 0.5 || p.Position.X > 2 {
+		t.Errorf("walking off the ledge ended at %v, on ground %v", p.Position, p.OnGround)
+	}
+}
+
+func TestPlayerWontStepUpTwoBlocks(t *testing.T) {
+	wall := func(p BlockPos) bool {
+		return solidFloor(p) || (p.X >= 3 && p.Y <= 2)
+	}
+
+	p := NewPlayer(rl.NewVector3(0, 0.5, 0))
+	stepPlayer(p, 120, rl.NewVector3(playerWalkSpeed, 0, 0), false, wall)
+	if want := rl.NewVector3(3-0.5-playerWidth/2, 0.5, 0); rl.Vector3Distance(p.Position, want) > 1e-5 {
+		t.Errorf("walking into a two block wall ended at %v, want %v", p.Position, want)
+	}
+}
+
+func TestPlayerWontStepUpInMidAir(t *testing.T) {
+	ledge := func(p BlockPos) bool {
+		return p.X >= 3 && p.Y == 1
+	}
+
+	// Falling past the side of the ledge doesn't climb it
+	p := NewPlayer(rl.NewVector3(2, 1.2, 0))
+	p.Velocity.Y = -1
+	p.Step(rl.NewVector3(playerWalkSpeed, 0, 0), false, tickDuration, ledge)
+	if p.Position.Y >= 1.2 {
+		t.Errorf("falling player stepped up to %v", p.Position)
+	}
+}
+
+func TestPlayerDoesntTunnel(t *testing.T) {
+	// Floors and walls one block thick
+	thin := func(p BlockPos) bool {
+		return p.Y == -50 || p.X == 10
+	}
+
+	// Long ticks at terminal velocity move several blocks each
+	p := NewPlayer(rl.NewVector3(0, 100, 0))
+	p.Velocity.Y = -terminalVelocity
+	for range 100 {
+		p.Step(rl.Vector3{}, false, 0.1, thin)
+		if p.Velocity.Y != 0 && p.Velocity.Y != -terminalVelocity {
+			t.Fatalf("falling velocity %v isn't capped at %v", p.Velocity.Y, -terminalVelocity)
+		}
+	}
+	if !p.OnGround || p.Position.Y != -49.5 {
+		t.Fatalf("player fell through the floor to %v", p.Position)
+	}
+
+	// Moving many blocks a tick sideways doesn't pass the wall either
+	p.Step(rl.NewVector3(900, 0, 0), false, 0.1, thin)
+	if want := float32(10 - 0.5 - playerWidth/2); math.Abs(float64(p.Position.X-want)) > 1e-5 {
+		t.Errorf("player ran to x = %v, want stopped at %v", p.Position.X, want)
+	}
+}
+
+func TestPlayerOccupies(t *testing.T) {
+	p := NewPlayer(rl.NewVector3(0, 0.5, 0))
+
+	// Feet in the voxel at y = 1, head in y = 2
+	for _, pos := range []BlockPos{{0, 1, 0}, {0, 2, 0}} {
+		if !p.Occupies(pos) {
+			t.Errorf("player doesn't occupy %v", pos)
+		}
+	}
+	for _, pos := range []BlockPos{{0, 0, 0}, {0, 3, 0}, {1, 1, 0}, {0, 1, -1}} {
+		if p.Occupies(pos) {
+			t.Errorf("player occupies %v", pos)
+		}
+	}
+
+	// Straddling a voxel border
+	p.Position.X = 0.5
+	if !p.Occupies(BlockPos{0, 1, 0}) || !p.Occupies(BlockPos{1, 1, 0}) {
+		t.Error("player on a border doesn't occupy both voxels")
+	}
+}
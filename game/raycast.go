@@ -0,0 +1,96 @@
+package game
+
+import (
+	"math"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// RaycastHit is the first voxel a ray runs into
+type RaycastHit struct {
+	Block BlockPos
+
+	// Normal points out of the face the ray entered through, so the block
+	// in front of that face is Block plus Normal. It's zero if the ray
+	// started inside the block
+	Normal BlockPos
+
+	// Distance along the ray to where it entered the block
+	Distance float32
+}
+
+// Raycast walks the voxels along the ray from origin and returns the first
+// one that isn't air within maxDistance. Chunks that aren't loaded are air
+func (w *World) Raycast(origin, direction rl.Vector3, maxDistance float32) (RaycastHit, bool) {
+	return raycastVoxels(origin, direction, maxDistance, func(pos BlockPos) bool {
+		return w.GetVoxel(pos) != air
+	})
+}
+
+// raycastVoxels steps through every voxel the ray passes through in order
+// using Amanatides and Woo's traversal, stopping at the first where hit
+// returns true
+func raycastVoxels(origin, direction rl.Vector3, maxDistance float32, hit func(BlockPos) bool) (RaycastHit, bool) {
+	if rl.Vector3Length(direction) == 0 {
+		return RaycastHit{}, false
+	}
+	direction = rl.Vector3Normalize(direction)
+
+	// Voxels are centered on their integer position, so shift the origin
+	// by half a block to make the voxel boundaries fall on integers
+	o := [3]float64{float64(origin.X) + 0.5, float64(origin.Y) + 0.5, float64(origin.Z) + 0.5}
+	d := [3]float64{float64(direction.X), float64(direction.Y), float64(direction.Z)}
+
+	// tMax is the distance along the ray to the next boundary on each axis
+	// and tDelta is the distance between boundaries on that axis
+	var block, step [3]int
+	var tMax, tDelta [3]float64
+	for i := range 3 {
+		block[i] = int(math.Floor(o[i]))
+
+		switch {
+		case d[i] > 0:
+			step[i] = 1
+			tMax[i] = (float64(block[i]+1) - o[i]) / d[i]
+			tDelta[i] = 1 / d[i]
+		case d[i] < 0:
+			step[i] = -1
+			tMax[i] = (float64(block[i]) - o[i]) / d[i]
+			tDelta[i] = -1 / d[i]
+		default:
+			tMax[i] = math.Inf(1)
+			tDelta[i] = math.Inf(1)
+		}
+	}
+
+	var normal [3]int
+	distance := 0.0
+	for distance <= float64(maxDistance) {
+		pos := BlockPos{X: block[0], Y: block[1], Z: block[2]}
+		if hit(pos) {
+			return RaycastHit{
+				Block:    pos,
+				Normal:   BlockPos{X: normal[0], Y: normal[1], Z: normal[2]},
+				Distance: float32(distance),
+			}, true
+		}
+
+		// Cross whichever boundary is closest
+		axis := 0
+		if tMax[1] < tMax[axis] {
+			axis = 1
+		}
+		if tMax[2] < tMax[axis] {
+			axis = 2
+		}
+
+		distance = tMax[axis]
+		block[axis] += step[axis]
+		tMax[axis] += tDelta[axis]
+
+		normal = [3]int{}
+		normal[axis] = -step[axis]
+	}
+
+	return RaycastHit{}, false
+}
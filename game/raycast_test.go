@@ -0,0 +1,182 @@
+package game
+
+import (
+	"math"
+	"testing"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+func TestRaycastVoxels(t *testing.T) {
+	tests := []struct {
+		name        string
+		origin, dir rl.Vector3
+		maxDistance float32
+		solid       func(BlockPos) bool
+		hit         bool
+		block       BlockPos
+		normal      BlockPos
+		distance    float64
+	}{
+		{
+			name:   "along +x",
+			origin: rl.NewVector3(0, 0, 0), dir: rl.NewVector3(1, 0, 0), maxDistance: 10,
+			solid: func(p BlockPos) bool { return p == BlockPos{5, 0, 0} },
+			hit:   true, block: BlockPos{5, 0, 0}, normal: BlockPos{-1, 0, 0}, distance: 4.5,
+		},
+		{
+			name:   "along -z",
+			origin: rl.NewVector3(0, 0, 0), dir: rl.NewVector3(0, 0, -3), maxDistance: 10,
+			solid: func(p BlockPos) bool { return p == BlockPos{0, 0, -3} },
+			hit:   true, block: BlockPos{0, 0, -3}, normal: BlockPos{0, 0, 1}, distance: 2.5,
+		},
+		{
+			name:   "down from off center",
+			origin: rl.NewVector3(0.2, 10.3, -0.1), dir: rl.NewVector3(0, -1, 0), maxDistance: 10,
+			solid: func(p BlockPos) bool { return p.Y <= 2 },
+			hit:   true, block: BlockPos{0, 2, 0}, normal: BlockPos{0, 1, 0}, distance: 7.8,
+		},
+		{
+			// y = x/2 reaches the wall's face at x = 3.5 with y = 1.75
+			name:   "diagonal",
+			origin: rl.NewVector3(0, 0, 0), dir: rl.NewVector3(2, 1, 0), maxDistance: 10,
+			solid: func(p BlockPos) bool { return p.X == 4 },
+			hit:   true, block: BlockPos{4, 2, 0}, normal: BlockPos{-1, 0, 0}, distance: 3.5 * math.Sqrt(5) / 2,
+		},
+		{
+			// Reaches z = -4.5 with x = -1.125 and y = -2.25
+			name:   "diagonal negative",
+			origin: rl.NewVector3(0, 0, 0), dir: rl.NewVector3(-1, -2, -4), maxDistance: 10,
+			solid: func(p BlockPos) bool { return p.Z == -5 },
+			hit:   true, block: BlockPos{-1, -2, -5}, normal: BlockPos{0, 0, 1}, distance: 4.5 * math.Sqrt(21) / 4,
+		},
+		{
+			name:   "floor from below",
+			origin: rl.NewVector3(3, -4, 3), dir: rl.NewVector3(0, 1, 0), maxDistance: 10,
+			solid: func(p BlockPos) bool { return p.Y >= 0 },
+			hit:   true, block: BlockPos{3, 0, 3}, normal: BlockPos{0, -1, 0}, distance: 3.5,
+		},
+		{
+			name:   "starting inside a block",
+			origin: rl.NewVector3(2.2, 0.1, -0.4), dir: rl.NewVector3(1, 1, 0), maxDistance: 10,
+			solid: func(p BlockPos) bool { return p == BlockPos{2, 0, 0} },
+			hit:   true, block: BlockPos{2, 0, 0}, normal: BlockPos{}, distance: 0,
+		},
+		{
+			name:   "at the reach",
+			origin: rl.NewVector3(0, 0, 0), dir: rl.NewVector3(1, 0, 0), maxDistance: 4.5,
+			solid: func(p BlockPos) bool { return p == BlockPos{5, 0, 0} },
+			hit:   true, block: BlockPos{5, 0, 0}, normal: BlockPos{-1, 0, 0}, distance: 4.5,
+		},
+		{
+			name:   "beyond the reach",
+			origin: rl.NewVector3(0, 0, 0), dir: rl.NewVector3(1, 0, 0), maxDistance: 4.4,
+			solid: func(p BlockPos) bool { return p == BlockPos{5, 0, 0} },
+		},
+		{
+			name:   "missing everything",
+			origin: rl.NewVector3(0, 0, 0), dir: rl.NewVector3(1, 1, 1), maxDistance: 50,
+			solid: func(p BlockPos) bool { return p.Y < -1 },
+		},
+		{
+			name:   "no direction",
+			origin: rl.NewVector3(0, 0, 0), dir: rl.NewVector3(0, 0, 0), maxDistance: 10,
+			solid: func(p BlockPos) bool { return true },
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			hit, ok := raycastVoxels(test.origin, test.dir, test.maxDistance, test.solid)
+			if ok != test.hit {
+				t.Fatalf("hit = %v at %+v, want %v", ok, hit, test.hit)
+			}
+			if !ok {
+				return
+			}
+			if hit.Block != test.block || hit.Normal != test.normal {
+				t.Errorf("hit %v with normal %v, want %v with normal %v", hit.Block, hit.Normal, test.block, test.normal)
+			}
+			if math.Abs(float64(hit.Distance)-test.distance) > 1e-4 {
+				t.Errorf("distance = %v, want %v", hit.Distance, test.distance)
+			}
+
+			// In front of the face hit is never solid, unless the ray
+			// started inside the block
+			front := BlockPos{X: hit.Block.X + hit.Normal.X, Y: hit.Block.Y + hit.Normal.Y, Z: hit.Block.Z + hit.Normal.Z}
+			if hit.Normal != (BlockPos{}) && test.solid(front) {
+				t.Errorf("block in front of the face hit, %v, is solid", front)
+			}
+		})
+	}
+}
+
+func TestRaycastVisitsNeighbouringVoxels(t *testing.T) {
+	for _, dir := range []rl.Vector3{
+		rl.NewVector3(1, 0.3, -0.7),
+		rl.NewVector3(-0.2, -1, 0.5),
+		rl.NewVector3(0.577, 0.577, 0.577),
+	} {
+		var visited []BlockPos
+		raycastVoxels(rl.NewVector3(0.3, -0.2, 0.1), dir, 20, func(pos BlockPos) bool {
+			visited = append(visited, pos)
+			return false
+		})
+
+		if visited[0] != (BlockPos{0, 0, 0}) {
+			t.Errorf("ray along %v started in %v", dir, visited[0])
+		}
+		for i := 1; i < len(visited); i++ {
+			a, b := visited[i-1], visited[i]
+			if abs(b.X-a.X)+abs(b.Y-a.Y)+abs(b.Z-a.Z) != 1 {
+				t.Fatalf("ray along %v jumped from %v to %v", dir, visited[i-1], visited[i])
+			}
+		}
+		if len(visited) < 20 {
+			t.Errorf("ray along %v visited only %d voxels in 20 blocks", dir, len(visited))
+		}
+	}
+}
+
+func abs(n int) int {
+	return max(n, -n)
+}
+
+func TestRaycastAcrossChunks(t *testing.T) {
+	world := newTestWorld(t, -100)
+	world.SetVoxel(BlockPos{X: 17, Y: 3, Z: 3}, stone)
+	world.SetVoxel(BlockPos{X: -2, Y: 3, Z: 3}, dirt)
+	world.SetVoxel(BlockPos{X: 3, Y: 3, Z: -20}, grass)
+
+	tests := []struct {
+		origin, dir   rl.Vector3
+		block, normal BlockPos
+		distance      float32
+	}{
+		{rl.NewVector3(10, 3, 3), rl.NewVector3(1, 0, 0), BlockPos{17, 3, 3}, BlockPos{-1, 0, 0}, 6.5},
+		{rl.NewVector3(1, 3, 3), rl.NewVector3(-1, 0, 0), BlockPos{-2, 3, 3}, BlockPos{1, 0, 0}, 2.5},
+		{rl.NewVector3(3, 3, 3), rl.NewVector3(0, 0, -1), BlockPos{3, 3, -20}, BlockPos{0, 0, 1}, 22.5},
+	}
+	for _, test := range tests {
+		hit, ok := world.Raycast(test.origin, test.dir, 30)
+		if !ok || hit.Block != test.block || hit.Normal != test.normal || hit.Distance != test.distance {
+			t.Errorf("Raycast from %v along %v = %+v, %v, want %v with normal %v at %v",
+				test.origin, test.dir, hit, ok, test.block, test.normal, test.distance)
+		}
+	}
+}
+
+func TestRaycastUnloadedChunks(t *testing.T) {
+	world := newTestWorld(t, 0)
+
+	// The stone below hasn't been loaded, so it's air
+	origin, down := rl.NewVector3(5, 10, 5), rl.NewVector3(0, -1, 0)
+	if hit, ok := world.Raycast(origin, down, 20); ok {
+		t.Fatalf("hit %v in a chunk that isn't loaded", hit.Block)
+	}
+
+	world.loadChunk(ChunkCoord{})
+	hit, ok := world.Raycast(origin, down, 20)
+	if !ok || hit.Block != (BlockPos{5, 0, 5}) || hit.Normal != (BlockPos{0, 1, 0}) || hit.Distance != 9.5 {
+		t.Errorf("Raycast = %+v, %v, want the top of the loaded stone", hit, ok)
+	}
+}
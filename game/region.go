@@ -0,0 +1,424 @@
+package game
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+const (
+	// regionSize is how many chunks a region file spans along x and z. Each
+	// section height gets its own region files
+	regionSize = 32
+
+	regionMagic          = "VXRG"
+	regionVersion uint16 = 1
+
+	regionSlots = regionSize * regionSize
+
+	// magic, version, reserved, slot table, table checksum
+	regionHeaderSize = 4 + 2 + 2 + regionSlots*regionSlotSize + 4
+	regionSlotSize   = 12
+)
+
+var (
+	ErrChunkNotSaved = errors.New("chunk not saved")
+	ErrRegionCorrupt = errors.New("region file corrupt")
+)
+
+// regionCoord addresses a region file
+type regionCoord struct {
+	X, Y, Z int32
+}
+
+func regionOf(coord ChunkCoord) (regionCoord, int) {
+	x := floorDiv(int(coord.X), regionSize)
+	z := floorDiv(int(coord.Z), regionSize)
+
+	slot := floorMod(int(coord.X), regionSize) + floorMod(int(coord.Z), regionSize)*regionSize
+
+	return regionCoord{X: int32(x), Y: coord.Y, Z: int32(z)}, slot
+}
+
+// regionSlot locates a chunk's compressed data in a region file
+type regionSlot struct {
+	Offset, Length uint32
+	Checksum       uint32 // CRC-32 of the compressed data
+}
+
+// regionTable is the offset table of a region file, by slot
+type regionTable [regionSlots]regionSlot
+
+// RegionStore saves chunks to region files in a directory. Each file holds
+// a header with a version and an offset table, followed by the zlib
+// compressed chunks. It's safe to use from multiple goroutines, and chunks
+// load in parallel
+type RegionStore struct {
+	dir string
+
+	// mu is held for reading while chunks load and for writing while region
+	// files are replaced
+	mu sync.RWMutex
+
+	// tables caches the offset table of each region file read so far,
+	// guarded by tablesMu since loads share mu
+	tablesMu sync.Mutex
+	tables   map[regionCoord]*regionTable
+}
+
+// Create a new region store in the directory, creating it if needed
+func NewRegionStore(dir string) (*RegionStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("creating region directory: %w", err)
+	}
+	return &RegionStore{
+		dir:    dir,
+		tables: make(map[regionCoord]*regionTable),
+	}, nil
+}
+
+func (s *RegionStore) path(r regionCoord) string {
+	return filepath.Join(s.dir, fmt.Sprintf("r.%d.%d.%d.vxr", r.X, r.Y, r.Z))
+}
+
+// Load reads the chunk from its region file. It returns ErrChunkNotSaved if
+// the chunk has never been saved, and ErrRegionCorrupt if its data or the
+// region's offset table is damaged. Only the offset table and the chunk's
+// own data are read
+func (s *RegionStore) Load(coord ChunkCoord) (*Chunk, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	region, index := regionOf(coord)
+	table, err := s.table(region)
+	if err != nil {
+		return nil, err
+	}
+
+	slot := table[index]
+	if slot.Length == 0 {
+		return nil, ErrChunkNotSaved
+	}
+
+	data, err := readSlot(s.path(region), slot)
+	if err != nil {
+		return nil, fmt.Errorf("chunk %s: %w", coord, err)
+	}
+
+	chunk := NewChunk(coord)
+	if err := decodeChunk(data, &chunk); err != nil {
+		return nil, fmt.Errorf("chunk %s: %w", coord, err)
+	}
+	return &chunk, nil
+}
+
+// Save writes the chunks to their region files, replacing any earlier saves
+func (s *RegionStore) Save(chunks []*Chunk) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	byRegion := make(map[regionCoord][]*Chunk)
+	for _, chunk := range chunks {
+		region, _ := regionOf(chunk.Coord)
+		byRegion[region] = append(byRegion[region], chunk)
+	}
+
+	for region, chunks := range byRegion {
+		existing, err := s.readRegion(region)
+		if errors.Is(err, ErrRegionCorrupt) {
+			// Without an offset table none of the chunks can be found, so
+			// keep the file around for inspection rather than failing every
+			// save to this region from now on
+			path := s.path(region)
+			if err := os.Rename(path, path+".corrupt"); err != nil {
+				return fmt.Errorf("moving corrupt region: %w", err)
+			}
+			existing = make(map[int][]byte)
+		} else if err != nil {
+			return err
+		}
+
+		for _, chunk := range chunks {
+			data, err := encodeChunk(chunk)
+			if err != nil {
+				return fmt.Errorf("chunk %s: %w", chunk.Coord, err)
+			}
+			_, slot := regionOf(chunk.Coord)
+			existing[slot] = data
+		}
+
+		table, err := s.writeRegion(region, existing)
+		s.tablesMu.Lock()
+		if err != nil {
+			delete(s.tables, region)
+		} else {
+			s.tables[region] = table
+		}
+		s.tablesMu.Unlock()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// table returns the region's offset table, reading it on first use. A
+// missing file has an empty table
+func (s *RegionStore) table(region regionCoord) (*regionTable, error) {
+	s.tablesMu.Lock()
+	defer s.tablesMu.Unlock()
+
+	if table, ok := s.tables[region]; ok {
+		return table, nil
+	}
+
+	table, err := readRegionTable(s.path(region))
+	if err != nil {
+		return nil, err
+	}
+	s.tables[region] = table
+	return table, nil
+}
+
+// readRegionTable reads and checks the header of the region file
+func readRegionTable(path string) (*regionTable, error) {
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &regionTable{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading region: %w", err)
+	}
+	defer file.Close()
+
+	header := make([]byte, regionHeaderSize)
+	if _, err := io.ReadFull(file, header); err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, fmt.Errorf("%w: bad header", ErrRegionCorrupt)
+		}
+		return nil, fmt.Errorf("reading region: %w", err)
+	}
+
+	if string(header[:4]) != regionMagic {
+		return nil, fmt.Errorf("%w: bad header", ErrRegionCorrupt)
+	}
+	if version := binary.LittleEndian.Uint16(header[4:]); version != regionVersion {
+		return nil, fmt.Errorf("unsupported region version %d", version)
+	}
+
+	raw := header[8 : regionHeaderSize-4]
+	if crc32.ChecksumIEEE(raw) != binary.LittleEndian.Uint32(header[regionHeaderSize-4:]) {
+		return nil, fmt.Errorf("%w: offset table checksum mismatch", ErrRegionCorrupt)
+	}
+
+	var table regionTable
+	for i := range table {
+		table[i] = regionSlot{
+			Offset:   binary.LittleEndian.Uint32(raw[i*regionSlotSize:]),
+			Length:   binary.LittleEndian.Uint32(raw[i*regionSlotSize+4:]),
+			Checksum: binary.LittleEndian.Uint32(raw[i*regionSlotSize+8:]),
+		}
+	}
+	return &table, nil
+}
+
+// readSlot reads and checks a single chunk's compressed data
+func readSlot(path string, slot regionSlot) ([]byte, error) {
+	if slot.Offset < regionHeaderSize {
+		return nil, fmt.Errorf("%w: slot out of bounds", ErrRegionCorrupt)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading region: %w", err)
+	}
+	defer file.Close()
+
+	data := make([]byte, slot.Length)
+	if _, err := file.ReadAt(data, int64(slot.Offset)); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("%w: slot out of bounds", ErrRegionCorrupt)
+		}
+		return nil, fmt.Errorf("reading region: %w", err)
+	}
+
+	if crc32.ChecksumIEEE(data) != slot.Checksum {
+		return nil, fmt.Errorf("%w: slot checksum mismatch", ErrRegionCorrupt)
+	}
+	return data, nil
+}
+
+// readRegion returns every intact compressed chunk in the region file by
+// slot. Chunks whose data is damaged are left out, so they're regenerated
+// instead of taking the rest of the region with them
+func (s *RegionStore) readRegion(region regionCoord) (map[int][]byte, error) {
+	chunks := make(map[int][]byte)
+
+	table, err := s.table(region)
+	if err != nil {
+		return nil, err
+	}
+	if *table == (regionTable{}) {
+		return chunks, nil
+	}
+
+	file, err := os.ReadFile(s.path(region))
+	if err != nil {
+		return nil, fmt.Errorf("reading region: %w", err)
+	}
+
+	for i, slot := range table {
+		if slot.Length == 0 {
+			continue
+		}
+
+		end := uint64(slot.Offset) + uint64(slot.Length)
+		if slot.Offset < regionHeaderSize || end > uint64(len(file)) {
+			log.Printf("dropping chunk in slot %d of %s: out of bounds", i, s.path(region))
+			continue
+		}
+
+		data := file[slot.Offset:end]
+		if crc32.ChecksumIEEE(data) != slot.Checksum {
+			log.Printf("dropping chunk in slot %d of %s: checksum mismatch", i, s.path(region))
+			continue
+		}
+		chunks[i] = data
+	}
+
+	return chunks, nil
+}
+
+// writeRegion replaces the region file with the compressed chunks,
+// returning its new offset table. The file is written alongside and renamed
+// into place so a crash can't leave it half written
+func (s *RegionStore) writeRegion(region regionCoord, chunks map[int][]byte) (*regionTable, error) {
+	var table regionTable
+	var raw [regionSlots * regionSlotSize]byte
+	var body bytes.Buffer
+
+	offset := uint32(regionHeaderSize)
+	for i := range regionSlots {
+		data, ok := chunks[i]
+		if !ok {
+			continue
+		}
+
+		table[i] = regionSlot{
+			Offset:   offset,
+			Length:   uint32(len(data)),
+			Checksum: crc32.ChecksumIEEE(data),
+		}
+		binary.LittleEndian.PutUint32(raw[i*regionSlotSize:], table[i].Offset)
+		binary.LittleEndian.PutUint32(raw[i*regionSlotSize+4:], table[i].Length)
+		binary.LittleEndian.PutUint32(raw[i*regionSlotSize+8:], table[i].Checksum)
+
+		body.Write(data)
+		offset += uint32(len(data))
+	}
+
+	var file bytes.Buffer
+	file.WriteString(regionMagic)
+	binary.Write(&file, binary.LittleEndian, regionVersion)
+	binary.Write(&file, binary.LittleEndian, uint16(0))
+	file.Write(raw[:])
+	binary.Write(&file, binary.LittleEndian, crc32.ChecksumIEEE(raw[:]))
+	file.Write(body.Bytes())
+
+	path := s.path(region)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, file.Bytes(), 0o644); err != nil {
+		return nil, fmt.Errorf("writing region: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return nil, fmt.Errorf("writing region: %w", err)
+	}
+
+	return &table, nil
+}
+
+// encodeChunk compresses the chunk's palette storage
+func encodeChunk(c *Chunk) ([]byte, error) {
+	var buf bytes.Buffer
+	w := zlib.NewWriter(&buf)
+
+	storage := &c.voxels
+	header := []any{
+		uint16(len(storage.palette)),
+		storage.palette,
+		storage.bits,
+		storage.data,
+	}
+	for _, field := range header {
+		if err := binary.Write(w, binary.LittleEndian, field); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// decodeChunk decompresses the data from encodeChunk into the chunk
+func decodeChunk(data []byte, c *Chunk) error {
+	zr, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrRegionCorrupt, err)
+	}
+	raw, err := io.ReadAll(zr)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrRegionCorrupt, err)
+	}
+	r := bytes.NewReader(raw)
+
+	var storage paletteStorage
+	var paletteLen uint16
+	if err := binary.Read(r, binary.LittleEndian, &paletteLen); err != nil {
+		return fmt.Errorf("%w: %w", ErrRegionCorrupt, err)
+	}
+	storage.palette = make([]VoxelType, paletteLen)
+	if err := binary.Read(r, binary.LittleEndian, storage.palette); err != nil {
+		return fmt.Errorf("%w: %w", ErrRegionCorrupt, err)
+	}
+	if err := binary.Read(r, binary.LittleEndian, &storage.bits); err != nil {
+		return fmt.Errorf("%w: %w", ErrRegionCorrupt, err)
+	}
+
+	validWidth := storage.bits == 0
+	for _, width := range paletteWidths {
+		validWidth = validWidth || storage.bits == width
+	}
+	if !validWidth || len(storage.palette) > 1<<storage.bits {
+		return fmt.Errorf("%w: bad palette", ErrRegionCorrupt)
+	}
+
+	storage.data = make([]uint64, chunkVolume*int(storage.bits)/64)
+	if err := binary.Read(r, binary.LittleEndian, storage.data); err != nil {
+		return fmt.Errorf("%w: %w", ErrRegionCorrupt, err)
+	}
+	if r.Len() != 0 {
+		return fmt.Errorf("%w: trailing data", ErrRegionCorrupt)
+	}
+
+	for i := range chunkVolume {
+		if storage.index(i) >= max(len(storage.palette), 1) {
+			return fmt.Errorf("%w: palette index out of range", ErrRegionCorrupt)
+		}
+	}
+	storage.recount()
+
+	c.voxels = storage
+	c.meshDirty = true
+	return nil
+}
@@ -0,0 +1,244 @@
+package game
+
+import (
+	"errors"
+	"os"
+	"sync"
+	"testing"
+)
+
+// savedChunk makes a chunk with a few voxels that depend on its coord
+func savedChunk(coord ChunkCoord) *Chunk {
+	chunk := NewChunk(coord)
+	chunk.setVoxel(0, 0, 0, stone)
+	chunk.setVoxel(int(coord.X)&15, 5, int(coord.Z)&15, dirt)
+	chunk.setVoxel(15, 15, 15, grass)
+	return &chunk
+}
+
+// sameVoxels compares every voxel of two chunks
+func sameVoxels(t *testing.T, got, want *Chunk) {
+	t.Helper()
+	for i := range chunkVolume {
+		if g, w := got.voxels.get(i), want.voxels.get(i); g != w {
+			t.Fatalf("chunk %s voxel %d = %v, want %v", want.Coord, i, g, w)
+		}
+	}
+}
+
+func TestRegionStoreRoundTrip(t *testing.T) {
+	store, err := NewRegionStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Spread over several regions, including negative ones
+	coords := []ChunkCoord{
+		{0, 0, 0}, {31, 0, 31}, {32, 0, 0}, {-1, 0, -1},
+		{-32, 1, 5}, {-33, 1, 5}, {7, 7, -100},
+	}
+	var chunks []*Chunk
+	for _, coord := range coords {
+		chunks = append(chunks, savedChunk(coord))
+	}
+	if err := store.Save(chunks); err != nil {
+		t.Fatal(err)
+	}
+
+	// A second store reads the files from scratch rather than its cache
+	fresh, err := NewRegionStore(store.dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []*RegionStore{store, fresh} {
+		for _, want := range chunks {
+			got, err := s.Load(want.Coord)
+			if err != nil {
+				t.Fatalf("Load(%s): %v", want.Coord, err)
+			}
+			if got.Coord != want.Coord || !got.meshDirty {
+				t.Errorf("loaded chunk %s isn't ready to mesh at %s", got.Coord, want.Coord)
+			}
+			sameVoxels(t, got, want)
+		}
+	}
+
+	if _, err := store.Load(ChunkCoord{X: 1}); !errors.Is(err, ErrChunkNotSaved) {
+		t.Errorf("Load of unsaved chunk in a saved region = %v, want ErrChunkNotSaved", err)
+	}
+	if _, err := store.Load(ChunkCoord{X: 1000}); !errors.Is(err, ErrChunkNotSaved) {
+		t.Errorf("Load of chunk in a missing region = %v, want ErrChunkNotSaved", err)
+	}
+}
+
+func TestRegionStoreResave(t *testing.T) {
+	store, err := NewRegionStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a, b := savedChunk(ChunkCoord{X: 1}), savedChunk(ChunkCoord{X: 2})
+	if err := store.Save([]*Chunk{a, b}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Saving one chunk again keeps the other
+	a.setVoxel(8, 8, 8, grass)
+	if err := store.Save([]*Chunk{a}); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []*Chunk{a, b} {
+		got, err := store.Load(want.Coord)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sameVoxels(t, got, want)
+	}
+}
+
+func TestRegionStoreCorruptSlot(t *testing.T) {
+	store, err := NewRegionStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	good, bad := savedChunk(ChunkCoord{X: 1}), savedChunk(ChunkCoord{X: 2})
+	if err := store.Save([]*Chunk{good, bad}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Flip a byte in the middle of one chunk's data
+	region, index := regionOf(bad.Coord)
+	path := store.path(region)
+	table, err := readRegionTable(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file[table[index].Offset+table[index].Length/2] ^= 0xff
+	if err := os.WriteFile(path, file, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := store.Load(bad.Coord); !errors.Is(err, ErrRegionCorrupt) {
+		t.Errorf("Load of damaged chunk = %v, want ErrRegionCorrupt", err)
+	}
+	got, err := store.Load(good.Coord)
+	if err != nil {
+		t.Fatalf("Load of intact chunk in the same region: %v", err)
+	}
+	sameVoxels(t, got, good)
+
+	// Saving to the region keeps the intact chunks and drops the damaged one
+	other := savedChunk(ChunkCoord{X: 3})
+	if err := store.Save([]*Chunk{other}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path + ".corrupt"); !errors.Is(err, os.ErrNotExist) {
+		t.Error("region with one damaged chunk was moved aside")
+	}
+	for _, want := range []*Chunk{good, other} {
+		got, err := store.Load(want.Coord)
+		if err != nil {
+			t.Fatalf("Load(%s) after saving: %v", want.Coord, err)
+		}
+		sameVoxels(t, got, want)
+	}
+	if _, err := store.Load(bad.Coord); !errors.Is(err, ErrChunkNotSaved) {
+		t.Errorf("Load of dropped chunk = %v, want ErrChunkNotSaved", err)
+	}
+}
+
+func TestRegionStoreCorruptHeader(t *testing.T) {
+	for name, damage := range map[string]func([]byte) []byte{
+		"magic":     func(file []byte) []byte { file[0] = 'X'; return file },
+		"table":     func(file []byte) []byte { file[20] ^= 0xff; return file },
+		"truncated": func(file []byte) []byte { return file[:100] },
+	} {
+		t.Run(name, func(t *testing.T) {
+			store, err := NewRegionStore(t.TempDir())
+			if err != nil {
+				t.Fatal(err)
+			}
+			chunk := savedChunk(ChunkCoord{})
+			if err := store.Save([]*Chunk{chunk}); err != nil {
+				t.Fatal(err)
+			}
+
+			region, _ := regionOf(chunk.Coord)
+			path := store.path(region)
+			file, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(path, damage(file), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			// Read without the cached table
+			store, err = NewRegionStore(store.dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := store.Load(chunk.Coord); !errors.Is(err, ErrRegionCorrupt) {
+				t.Fatalf("Load = %v, want ErrRegionCorrupt", err)
+			}
+
+			// Saving moves the unreadable file aside and starts over
+			if err := store.Save([]*Chunk{chunk}); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := os.Stat(path + ".corrupt"); err != nil {
+				t.Errorf("corrupt region wasn't kept: %v", err)
+			}
+			got, err := store.Load(chunk.Coord)
+			if err != nil {
+				t.Fatal(err)
+			}
+			sameVoxels(t, got, chunk)
+		})
+	}
+}
+
+func TestRegionStoreConcurrentLoads(t *testing.T) {
+	store, err := NewRegionStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var chunks []*Chunk
+	for i := range 32 {
+		chunks = append(chunks, savedChunk(ChunkCoord{X: int32(i), Z: int32(i % 3)}))
+	}
+	if err := store.Save(chunks); err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for _, want := range chunks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			got, err := store.Load(want.Coord)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			for i := range chunkVolume {
+				if got.voxels.get(i) != want.voxels.get(i) {
+					t.Errorf("chunk %s differs at voxel %d", want.Coord, i)
+					return
+				}
+			}
+		}()
+	}
+
+	// Saving while loading replaces the files underneath the loads
+	if err := store.Save(chunks[:4]); err != nil {
+		t.Error(err)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,22 @@
+#version 330
+
+// Repeats the face's texture once per voxel by wrapping its position into
+// the texture's atlas rectangle
+
+in vec2 fragTexCoord;
+flat in vec4 fragTile;
+
+uniform sampler2D texture0;
+uniform vec4 colDiffuse;
+
+out vec4 finalColor;
+
+void main()
+{
+    vec2 uv = mix(fragTile.xy, fragTile.zw, fract(fragTexCoord));
+
+    // The wrapped coordinates jump at every voxel edge, so take the
+    // derivatives from the unwrapped ones to keep the sampling smooth
+    vec2 size = fragTile.zw - fragTile.xy;
+    finalColor = textureGrad(texture0, uv, dFdx(fragTexCoord)*size, dFdy(fragTexCoord)*size)*colDiffuse;
+}
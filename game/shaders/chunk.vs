@@ -0,0 +1,20 @@
+#version 330
+
+// Chunk meshes carry each face's position across its texture in voxels,
+// and the atlas rectangle of the texture in the tangent attribute
+
+in vec3 vertexPosition;
+in vec2 vertexTexCoord;
+in vec4 vertexTangent;
+
+uniform mat4 mvp;
+
+out vec2 fragTexCoord;
+flat out vec4 fragTile;
+
+void main()
+{
+    fragTexCoord = vertexTexCoord;
+    fragTile = vertexTangent;
+    gl_Position = mvp*vec4(vertexPosition, 1.0);
+}
@@ -0,0 +1,129 @@
+package game
+
+import (
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+const (
+	// tickRate is how many times per second the Simulation is advanced
+	tickRate = 60
+
+	// tickDuration is the time simulated by one tick, in seconds
+	tickDuration = 1.0 / tickRate
+
+	// maxFrameTime is the most time simulated after one frame
+	maxFrameTime = 0.25
+
+	// blockReach is how far away blocks can be broken and placed
+	blockReach = 6
+)
+
+// TickInput is what the player asked for during one tick
+type TickInput struct {
+	Move moveInput
+
+	// Look is the direction the camera faces
+	Look rl.Vector3
+
+	// Break and Place are set once per click. Place puts a block of the
+	// Selected type against the targeted face
+	Break, Place bool
+	Selected     VoxelType
+
+	// ToggleWalking switches between flying and walking
+	ToggleWalking bool
+}
+
+// Simulation is the state of the game that advances in fixed ticks: the
+// player's movement and physics, and block edits. It never talks to the
+// window, so it runs the same headless as it does behind the renderer
+type Simulation struct {
+	World  *World
+	Player *Player
+
+	// Eye is where the camera is after the latest tick and prevEye is where
+	// it was before, so rendering can blend between them
+	Eye     rl.Vector3
+	prevEye rl.Vector3
+
+	// Walking moves the Player under gravity instead of flying the camera
+	// through terrain
+	Walking bool
+
+	// Target is the block looked at within reach, if Targeting
+	Target    RaycastHit
+	Targeting bool
+
+	// Ticks counts every Tick so far
+	Ticks uint64
+}
+
+// Create a new simulation of the world with the camera at eye
+func NewSimulation(world *World, eye rl.Vector3) *Simulation {
+	return &Simulation{
+		World:   world,
+		Player:  NewPlayer(rl.Vector3Subtract(eye, rl.NewVector3(0, playerEyeHeight, 0))),
+		Eye:     eye,
+		prevEye: eye,
+	}
+}
+
+// Tick advances the simulation by tickDuration
+func (s *Simulation) Tick(in TickInput) {
+	s.prevEye = s.Eye
+	s.Ticks++
+
+	if in.ToggleWalking {
+		s.Walking = !s.Walking
+		if s.Walking {
+			s.Player.Position = rl.Vector3Subtract(s.Eye, rl.NewVector3(0, playerEyeHeight, 0))
+			s.Player.Velocity = rl.Vector3{}
+		}
+	}
+
+	if s.Walking {
+		s.walk(in)
+	} else {
+		s.Eye = rl.Vector3Add(s.Eye, flyDisplacement(in.Look, worldUp, in.Move, tickDuration))
+	}
+
+	s.editBlocks(in)
+}
+
+// InterpolatedEye returns the camera position part way between the last two
+// ticks, where alpha is how far through the next tick the renderer is
+func (s *Simulation) InterpolatedEye(alpha float32) rl.Vector3 {
+	return rl.Vector3Lerp(s.prevEye, s.Eye, alpha)
+}
+
+// walk steps the player and puts the camera at their eyes. The player
+// waits in place until the chunk they're in loads
+func (s *Simulation) walk(in TickInput) {
+	if _, ok := s.World.Chunks[blockAt(s.Player.Position).Chunk()]; ok {
+		s.Player.Step(walkVelocity(in.Look, worldUp, in.Move), in.Move.Up, tickDuration, s.World.Solid)
+	}
+	s.Eye = s.Player.Eye()
+}
+
+// editBlocks finds the block being looked at, breaking it or placing the
+// selected block against the targeted face
+func (s *Simulation) editBlocks(in TickInput) {
+	s.Target, s.Targeting = s.World.Raycast(s.Eye, in.Look, blockReach)
+	if !s.Targeting {
+		return
+	}
+
+	if in.Break {
+		s.World.SetVoxel(s.Target.Block, air)
+		return
+	}
+
+	// Place in front of the face looked at, unless the camera is inside
+	// the targeted block or the new one would overlap the camera or player
+	if in.Place && s.Target.Normal != (BlockPos{}) {
+		pos := s.Target.Block.Add(s.Target.Normal)
+		if pos != blockAt(s.Eye) && !(s.Walking && s.Player.Occupies(pos)) {
+			s.World.SetVoxel(pos, in.Selected)
+		}
+	}
+}
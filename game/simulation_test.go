@@ -0,0 +1,199 @@
+package game
+
+import (
+	"testing"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+var (
+	lookForward = rl.NewVector3(0, 0, 1)
+	lookDown    = rl.NewVector3(0, -1, 0)
+)
+
+// newTestSimulation starts a simulation with the camera at eye over stone
+// up to y = 0, with the chunks around the origin loaded
+func newTestSimulation(t *testing.T, eye rl.Vector3) *Simulation {
+	world := newTestWorld(t, 0)
+	for x := int32(-2); x <= 2; x++ {
+		for z := int32(-2); z <= 2; z++ {
+			for y := int32(-1); y <= 1; y++ {
+				world.loadChunk(ChunkCoord{X: x, Y: y, Z: z})
+			}
+		}
+	}
+	return NewSimulation(world, eye)
+}
+
+// testScript flies, edits some blocks, then walks around
+func testScript() []TickInput {
+	var script []TickInput
+	repeat := func(n int, in TickInput) {
+		for range n {
+			script = append(script, in)
+		}
+	}
+
+	repeat(60, TickInput{Move: moveInput{Forward: true}, Look: lookForward})
+	repeat(30, TickInput{Move: moveInput{Right: true, Down: true}, Look: rl.NewVector3(1, 0, 1)})
+	script = append(script,
+		TickInput{Look: lookDown, Break: true},
+		TickInput{Look: lookDown, Place: true, Selected: dirt},
+		TickInput{Look: rl.NewVector3(1, -1, 0), Break: true},
+		TickInput{Look: lookForward, ToggleWalking: true},
+	)
+	repeat(90, TickInput{Move: moveInput{Forward: true}, Look: rl.NewVector3(-1, -0.5, 0.2)})
+	repeat(20, TickInput{Move: moveInput{Up: true, Left: true}, Look: lookForward})
+	repeat(60, TickInput{Move: moveInput{Back: true, Sprint: true}, Look: lookForward})
+	return script
+}
+
+func TestSimulationIsDeterministic(t *testing.T) {
+	script := testScript()
+	a := newTestSimulation(t, rl.NewVector3(0, 5, 0))
+	b := newTestSimulation(t, rl.NewVector3(0, 5, 0))
+
+	for i, in := range script {
+		a.Tick(in)
+		b.Tick(in)
+		if a.Eye != b.Eye || a.Player.Position != b.Player.Position || a.Player.Velocity != b.Player.Velocity {
+			t.Fatalf("tick %d: simulations diverged, eyes at %v and %v", i, a.Eye, b.Eye)
+		}
+	}
+	if a.Ticks != uint64(len(script)) {
+		t.Errorf("Ticks = %d, want %d", a.Ticks, len(script))
+	}
+
+	for coord, chunk := range a.World.Chunks {
+		other := b.World.Chunks[coord]
+		if chunk.modified != other.modified {
+			t.Fatalf("chunk %s edited in one simulation only", coord)
+		}
+		for i := range chunkVolume {
+			if chunk.voxels.get(i) != other.voxels.get(i) {
+				t.Fatalf("chunk %s differs at voxel %d", coord, i)
+			}
+		}
+	}
+}
+
+func TestSimulationFlies(t *testing.T) {
+	s := newTestSimulation(t, rl.NewVector3(0, 5, 0))
+
+	for range tickRate {
+		s.Tick(TickInput{Move: moveInput{Forward: true}, Look: lookForward})
+	}
+	if want := rl.NewVector3(0, 5, flySpeed); rl.Vector3Distance(s.Eye, want) > 1e-3 {
+		t.Errorf("flew forward for a second to %v, want %v", s.Eye, want)
+	}
+
+	// Flying goes straight through the ground
+	for range tickRate {
+		s.Tick(TickInput{Move: moveInput{Down: true, Sprint: true}, Look: lookForward})
+	}
+	if want := rl.NewVector3(0, 5-flySprintSpeed, flySpeed); rl.Vector3Distance(s.Eye, want) > 1e-3 {
+		t.Errorf("flew down for a second to %v, want %v", s.Eye, want)
+	}
+
+	if got := s.InterpolatedEye(0); got != s.prevEye {
+		t.Errorf("InterpolatedEye(0) = %v, want the previous tick's %v", got, s.prevEye)
+	}
+	if got := s.InterpolatedEye(1); got != s.Eye {
+		t.Errorf("InterpolatedEye(1) = %v, want %v", got, s.Eye)
+	}
+}
+
+func TestSimulationWalks(t *testing.T) {
+	s := newTestSimulation(t, rl.NewVector3(0, 5, 0))
+
+	// Drops from the camera onto the ground
+	s.Tick(TickInput{Look: lookForward, ToggleWalking: true})
+	for range tickRate {
+		s.Tick(TickInput{Look: lookForward})
+	}
+	if !s.Walking || !s.Player.OnGround || s.Eye != rl.NewVector3(0, 0.5+playerEyeHeight, 0) {
+		t.Fatalf("walking player's eye is at %v, on ground %v", s.Eye, s.Player.OnGround)
+	}
+
+	// Looking down doesn't slow them
+	for range tickRate {
+		s.Tick(TickInput{Move: moveInput{Forward: true}, Look: rl.NewVector3(0, -3, 1)})
+	}
+	if want := rl.NewVector3(0, 0.5+playerEyeHeight, playerWalkSpeed); rl.Vector3Distance(s.Eye, want) > 1e-3 {
+		t.Errorf("walked forward for a second to %v, want %v", s.Eye, want)
+	}
+
+	// Back to flying from where the player is
+	s.Tick(TickInput{Look: lookForward, ToggleWalking: true})
+	s.Tick(TickInput{Move: moveInput{Up: true}, Look: lookForward})
+	if s.Walking || s.Eye.Y <= 0.5+playerEyeHeight {
+		t.Errorf("didn't fly up after toggling, eye at %v", s.Eye)
+	}
+}
+
+func TestSimulationWaitsForChunks(t *testing.T) {
+	world := newTestWorld(t, 0)
+	s := NewSimulation(world, rl.NewVector3(0, 5, 0))
+
+	// Nothing is loaded under the player, so they don't fall forever
+	s.Tick(TickInput{Look: lookForward, ToggleWalking: true})
+	for range tickRate {
+		s.Tick(TickInput{Move: moveInput{Forward: true}, Look: lookForward})
+	}
+	if s.Eye != rl.NewVector3(0, 5, 0) {
+		t.Errorf("player moved to %v without their chunk loaded", s.Eye)
+	}
+}
+
+func TestSimulationEditsBlocks(t *testing.T) {
+	s := newTestSimulation(t, rl.NewVector3(0, 3, 0))
+
+	s.Tick(TickInput{Look: lookDown})
+	if !s.Targeting || s.Target.Block != (BlockPos{0, 0, 0}) || s.Target.Normal != (BlockPos{0, 1, 0}) {
+		t.Fatalf("targeting %+v, %v, want the top of the ground below", s.Target, s.Targeting)
+	}
+
+	s.Tick(TickInput{Look: lookDown, Break: true})
+	if got := s.World.GetVoxel(BlockPos{0, 0, 0}); got != air {
+		t.Fatalf("broken block is %v", got)
+	}
+	if s.Target.Block != (BlockPos{0, 0, 0}) {
+		t.Errorf("target is %v after breaking it", s.Target.Block)
+	}
+
+	// Placing goes on top of the block now targeted beneath the hole
+	s.Tick(TickInput{Look: lookDown, Place: true, Selected: dirt})
+	if got := s.World.GetVoxel(BlockPos{0, 0, 0}); got != dirt {
+		t.Errorf("placed block is %v, want dirt", got)
+	}
+
+	// Nothing to place against within reach
+	s.Tick(TickInput{Look: rl.NewVector3(0, 1, 0), Place: true, Selected: dirt})
+	if s.Targeting {
+		t.Errorf("targeting %v looking at the sky", s.Target.Block)
+	}
+
+	// Breaking only happens on the tick that asks for it
+	s.Tick(TickInput{Look: lookDown})
+	if got := s.World.GetVoxel(BlockPos{0, 0, 0}); got != dirt {
+		t.Errorf("block is %v after a tick without clicks, want dirt", got)
+	}
+}
+
+func TestSimulationWontPlaceInPlayer(t *testing.T) {
+	s := newTestSimulation(t, rl.NewVector3(0, 5, 0))
+	s.Tick(TickInput{Look: lookForward, ToggleWalking: true})
+	for range tickRate {
+		s.Tick(TickInput{Look: lookForward})
+	}
+
+	// Looking at the ground at the player's feet targets the block under
+	// them, and the block above it is where they're standing
+	s.Tick(TickInput{Look: rl.NewVector3(0, -1, 0.01), Place: true, Selected: dirt})
+	if s.Target.Block != (BlockPos{0, 0, 0}) {
+		t.Fatalf("targeting %v, want the block under the player", s.Target.Block)
+	}
+	if got := s.World.GetVoxel(BlockPos{0, 1, 0}); got != air {
+		t.Errorf("placed %v inside the player", got)
+	}
+}
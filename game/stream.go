@@ -0,0 +1,135 @@
+package game
+
+import (
+	"math"
+	"sort"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// blockAt returns the voxel containing the position. Voxels are centered
+// on their integer position
+func blockAt(pos rl.Vector3) BlockPos {
+	return BlockPos{
+		X: int(math.Floor(float64(pos.X) + 0.5)),
+		Y: int(math.Floor(float64(pos.Y) + 0.5)),
+		Z: int(math.Floor(float64(pos.Z) + 0.5)),
+	}
+}
+
+// columnRequest is a column of chunks waiting to be loaded
+type columnRequest struct {
+	x, z      int32
+	distance  float64
+	inFrustum bool
+}
+
+// Stream requests the columns of chunks within ViewRadius of the camera from
+// the workers and unloads the ones beyond UnloadRadius. Columns in view of
+// the camera are requested first, then the closest ones. Edited chunks are
+// saved as they're unloaded
+func (w *World) Stream(camera *Camera) error {
+	center := blockAt(camera.Camera3D.Position).Chunk()
+
+	columnDistance := func(x, z int32) float64 {
+		return math.Hypot(float64(x-center.X), float64(z-center.Z))
+	}
+
+	var unloaded, modified []*Chunk
+	for coord, chunk := range w.Chunks {
+		if columnDistance(coord.X, coord.Z) > float64(w.UnloadRadius) {
+			chunk.unload()
+			delete(w.Chunks, coord)
+
+			unloaded = append(unloaded, chunk)
+			if chunk.modified {
+				modified = append(modified, chunk)
+			}
+		}
+	}
+	if err := w.saveChunks(modified); err != nil {
+		return err
+	}
+
+	// Neighbours culled their faces against the unloaded chunks, which are
+	// air now, so those faces need adding back
+	for _, chunk := range unloaded {
+		w.dirtyNeighbours(chunk.Coord)
+	}
+
+	// Chunks still being generated may have fallen out of range too
+	w.Workers.CancelWhere(func(coord ChunkCoord) bool {
+		return columnDistance(coord.X, coord.Z) > float64(w.UnloadRadius)
+	})
+
+	var requests []columnRequest
+	radius := int32(w.ViewRadius)
+	for x := center.X - radius; x <= center.X+radius; x++ {
+		for z := center.Z - radius; z <= center.Z+radius; z++ {
+			distance := columnDistance(x, z)
+			if distance > float64(w.ViewRadius) || w.columnRequested(x, z) {
+				continue
+			}
+
+			requests = append(requests, columnRequest{
+				x:         x,
+				z:         z,
+				distance:  distance,
+				inFrustum: camera.Frustum.Viewable(w.columnBoundingBox(x, z)),
+			})
+		}
+	}
+
+	sort.Slice(requests, func(i, j int) bool {
+		if requests[i].inFrustum != requests[j].inFrustum {
+			return requests[i].inFrustum
+		}
+		return requests[i].distance < requests[j].distance
+	})
+
+	// Queue as much as the workers will take, the rest is requested again
+	// next frame
+	for _, request := range requests {
+		for y := range w.Sections {
+			coord := ChunkCoord{X: request.x, Y: int32(y), Z: request.z}
+			if _, ok := w.Chunks[coord]; ok || w.Workers.Pending(coord) {
+				continue
+			}
+			if !w.Workers.Request(coord) {
+				return nil
+			}
+		}
+	}
+
+	return nil
+}
+
+// columnRequested reports whether every section of the column is either
+// loaded or being generated
+func (w *World) columnRequested(x, z int32) bool {
+	for y := range w.Sections {
+		coord := ChunkCoord{X: x, Y: int32(y), Z: z}
+		if _, ok := w.Chunks[coord]; !ok && !w.Workers.Pending(coord) {
+			return false
+		}
+	}
+	return true
+}
+
+// columnBoundingBox covers every section of the column
+func (w *World) columnBoundingBox(x, z int32) rl.BoundingBox {
+	origin := ChunkCoord{X: x, Z: z}.Origin()
+
+	return rl.BoundingBox{
+		Min: rl.NewVector3(
+			float32(origin.X)-0.5,
+			-0.5,
+			float32(origin.Z)-0.5,
+		),
+		Max: rl.NewVector3(
+			float32(origin.X+int(chunkLength))-0.5,
+			float32(w.Sections*int(chunkHeight))-0.5,
+			float32(origin.Z+int(chunkLength))-0.5,
+		),
+	}
+}
@@ -0,0 +1,206 @@
+package game
+
+import (
+	"math"
+	"slices"
+	"testing"
+	"time"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// orderedGenerator is a flat generator that records the columns it
+// generates chunks for, in order. It's only safe to use from one worker
+type orderedGenerator struct {
+	columns [][2]int32
+}
+
+func (g *orderedGenerator) Generate(c *Chunk) {
+	column := [2]int32{c.Coord.X, c.Coord.Z}
+	if !slices.Contains(g.columns, column) {
+		g.columns = append(g.columns, column)
+	}
+	FlatGenerator{Height: 0, Type: stone}.Generate(c)
+}
+
+// streamCamera is a camera in the middle of the column. When seeFrom is set
+// its frustum only sees the columns with x >= seeFrom, otherwise it sees
+// everything
+func streamCamera(column [2]int32, seeFrom *int32) *Camera {
+	origin := ChunkCoord{X: column[0], Z: column[1]}.Origin()
+	camera := &Camera{
+		Camera3D: rl.Camera3D{
+			Position: rl.NewVector3(float32(origin.X)+8, 8, float32(origin.Z)+8),
+		},
+	}
+	if seeFrom != nil {
+		camera.Frustum.left = Plane{
+			normal:   rl.NewVector3(1, 0, 0),
+			distance: float32(*seeFrom) * float32(chunkLength),
+		}
+	}
+	return camera
+}
+
+// newStreamWorld creates a world streaming columns two sections tall within
+// 2 chunks of the camera, and unloading them past 3. Chunks are generated by
+// a single worker so they complete in the order they're requested
+func newStreamWorld(t *testing.T, generator ChunkGenerator) *World {
+	world := NewWorld(generator, nil, DefaultBlockRegistry(), nil)
+	world.Sections = 2
+	world.ViewRadius, world.UnloadRadius = 2, 3
+
+	world.Workers.Close()
+	world.Workers = NewChunkWorkers(generator, nil, DefaultBlockRegistry(), nil, 1, defaultChunkQueueSize)
+	closeWorld(t, world)
+	return world
+}
+
+// streamAll streams until every column in range has loaded
+func streamAll(t *testing.T, world *World, camera *Camera) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		world.Stream(camera)
+		if len(world.Workers.pending) == 0 {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%d chunks never finished loading", len(world.Workers.pending))
+		}
+
+		time.Sleep(time.Millisecond)
+		for _, chunk := range world.Workers.Completed() {
+			world.addChunk(chunk)
+		}
+	}
+}
+
+// loadedColumns returns the columns with every section loaded, and fails
+// the test if any column is only partly loaded
+func loadedColumns(t *testing.T, world *World) [][2]int32 {
+	sections := make(map[[2]int32]int)
+	for coord := range world.Chunks {
+		sections[[2]int32{coord.X, coord.Z}]++
+	}
+
+	var columns [][2]int32
+	for column, n := range sections {
+		if n != world.Sections {
+			t.Errorf("column %v has %d of %d sections loaded", column, n, world.Sections)
+		}
+		columns = append(columns, column)
+	}
+	return columns
+}
+
+func sortedColumns(columns [][2]int32) [][2]int32 {
+	columns = slices.Clone(columns)
+	slices.SortFunc(columns, func(a, b [2]int32) int {
+		if a[0] != b[0] {
+			return int(a[0] - b[0])
+		}
+		return int(a[1] - b[1])
+	})
+	return columns
+}
+
+func TestStream(t *testing.T) {
+	two, five := int32(2), int32(5)
+
+	// Every column within 2 chunks of (0, 0)
+	around := [][2]int32{
+		{-2, 0},
+		{-1, -1}, {-1, 0}, {-1, 1},
+		{0, -2}, {0, -1}, {0, 0}, {0, 1}, {0, 2},
+		{1, -1}, {1, 0}, {1, 1},
+		{2, 0},
+	}
+
+	tests := []struct {
+		name     string
+		from     *[2]int32 // where the world was streamed in before
+		to       [2]int32
+		seeFrom  *int32
+		loaded   [][2]int32
+		unloaded [][2]int32
+	}{
+		{"new world", nil, [2]int32{0, 0}, nil, around, nil},
+		{"frustum first", nil, [2]int32{0, 0}, &two, around, nil},
+
+		// The columns left behind are still within the unload radius
+		{
+			"one step", &[2]int32{0, 0}, [2]int32{1, 0}, nil,
+			[][2]int32{{1, -2}, {1, 2}, {2, -1}, {2, 1}, {3, 0}},
+			nil,
+		},
+		{
+			"four steps", &[2]int32{0, 0}, [2]int32{4, 0}, &five,
+			[][2]int32{{3, -1}, {3, 0}, {3, 1}, {4, -2}, {4, -1}, {4, 0}, {4, 1}, {4, 2}, {5, -1}, {5, 0}, {5, 1}, {6, 0}},
+			[][2]int32{{-2, 0}, {-1, -1}, {-1, 0}, {-1, 1}, {0, -2}, {0, -1}, {0, 0}, {0, 1}, {0, 2}, {1, -1}, {1, 1}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			generator := &orderedGenerator{}
+			world := newStreamWorld(t, generator)
+
+			var before [][2]int32
+			if test.from != nil {
+				streamAll(t, world, streamCamera(*test.from, nil))
+				before = loadedColumns(t, world)
+				generator.columns = nil
+			}
+
+			streamAll(t, world, streamCamera(test.to, test.seeFrom))
+			after := loadedColumns(t, world)
+
+			if got := sortedColumns(generator.columns); !slices.Equal(got, test.loaded) {
+				t.Errorf("loaded %v, want %v", got, test.loaded)
+			}
+			var unloaded [][2]int32
+			for _, column := range before {
+				if !slices.Contains(after, column) {
+					unloaded = append(unloaded, column)
+				}
+			}
+			if got := sortedColumns(unloaded); !slices.Equal(got, test.unloaded) {
+				t.Errorf("unloaded %v, want %v", got, test.unloaded)
+			}
+
+			// Columns in view come first, then the closest
+			priority := func(column [2]int32) float64 {
+				distance := math.Hypot(float64(column[0]-test.to[0]), float64(column[1]-test.to[1]))
+				if test.seeFrom != nil && column[0] < *test.seeFrom {
+					distance += 100
+				}
+				return distance
+			}
+			for i := 1; i < len(generator.columns); i++ {
+				if prev, next := generator.columns[i-1], generator.columns[i]; priority(prev) > priority(next) {
+					t.Errorf("loaded %v before %v", prev, next)
+				}
+			}
+		})
+	}
+}
+
+func TestStreamRemeshesNeighboursOfUnloadedChunks(t *testing.T) {
+	world := newStreamWorld(t, FlatGenerator{Height: 0, Type: stone})
+
+	streamAll(t, world, streamCamera([2]int32{0, 0}, nil))
+	for _, chunk := range world.Chunks {
+		chunk.meshDirty = false
+	}
+
+	// Moving 4 chunks along x unloads (0, 0) and (1, ±1), but (1, 0) stays
+	// without any new neighbours being loaded
+	streamAll(t, world, streamCamera([2]int32{4, 0}, nil))
+	if _, ok := world.Chunks[ChunkCoord{}]; ok {
+		t.Fatal("chunk (0, 0) wasn't unloaded")
+	}
+	if chunk := world.Chunks[ChunkCoord{X: 1}]; !chunk.meshDirty {
+		t.Error("neighbour of an unloaded chunk wasn't marked for remeshing")
+	}
+}
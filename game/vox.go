@@ -0,0 +1,415 @@
+package game
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image/color"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// MagicaVoxel .vox files, see
+// https://github.com/ephtracy/voxel-model/blob/master/MagicaVoxel-file-format-vox.txt
+
+var errVoxTruncated = errors.New("vox: unexpected end of data")
+
+// VoxModel is a single model from a .vox file
+type VoxModel struct {
+	Size   [3]int
+	Voxels []VoxVoxel
+}
+
+// VoxVoxel is a voxel in model space (z up) with an index into the palette.
+// Index 0 is never used
+type VoxVoxel struct {
+	X, Y, Z, ColorIndex uint8
+}
+
+// VoxInstance places a model in the scene. Rotation and Translation are in
+// .vox space, and models are rotated around their center
+type VoxInstance struct {
+	Model       int
+	Rotation    [3][3]int
+	Translation [3]int
+}
+
+// VoxScene is the parsed content of a .vox file
+type VoxScene struct {
+	Models  []VoxModel
+	Palette [256]color.RGBA
+
+	// Instances are the placements from the scene graph. Files without one
+	// have no instances and each model sits at the origin
+	Instances []VoxInstance
+}
+
+// voxNode is a node of the scene graph
+type voxNode struct {
+	kind        string // nTRN, nGRP or nSHP
+	children    []int
+	models      []int
+	rotation    [3][3]int
+	translation [3]int
+}
+
+// voxReader reads little endian values from a .vox file
+type voxReader struct {
+	data []byte
+	err  error
+}
+
+func (r *voxReader) bytes(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if n < 0 || n > len(r.data) {
+		r.err = errVoxTruncated
+		return nil
+	}
+	b := r.data[:n]
+	r.data = r.data[n:]
+	return b
+}
+
+func (r *voxReader) int32() int {
+	b := r.bytes(4)
+	if b == nil {
+		return 0
+	}
+	return int(int32(binary.LittleEndian.Uint32(b)))
+}
+
+func (r *voxReader) string() string {
+	return string(r.bytes(r.int32()))
+}
+
+// chunk reads a chunk's id, content and children
+func (r *voxReader) chunk() (id string, content, children []byte) {
+	id = string(r.bytes(4))
+	contentSize, childrenSize := r.int32(), r.int32()
+	return id, r.bytes(contentSize), r.bytes(childrenSize)
+}
+
+func (r *voxReader) dict() map[string]string {
+	n := r.int32()
+	dict := make(map[string]string)
+	for i := 0; i < n && r.err == nil; i++ {
+		key := r.string()
+		dict[key] = r.string()
+	}
+	return dict
+}
+
+// ParseVox reads a .vox file including multi model scenes
+func ParseVox(reader io.Reader) (*VoxScene, error) {
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &voxReader{data: data}
+	if string(r.bytes(4)) != "VOX " {
+		return nil, errors.New("vox: not a .vox file")
+	}
+	r.int32() // version
+
+	id, _, children := r.chunk()
+	if r.err != nil {
+		return nil, r.err
+	}
+	if id != "MAIN" {
+		return nil, fmt.Errorf("vox: expected MAIN chunk, got %q", id)
+	}
+	r = &voxReader{data: children}
+
+	scene := &VoxScene{Palette: defaultVoxPalette()}
+	nodes := make(map[int]*voxNode)
+	var size [3]int
+
+	for len(r.data) > 0 && r.err == nil {
+		id, data, _ := r.chunk()
+		if r.err != nil {
+			break
+		}
+		content := &voxReader{data: data}
+
+		switch id {
+		case "SIZE":
+			size = [3]int{content.int32(), content.int32(), content.int32()}
+		case "XYZI":
+			// Check the count against the content before allocating for it
+			n := content.int32()
+			if n < 0 {
+				content.err = fmt.Errorf("vox: negative voxel count %d", n)
+				break
+			}
+			if n > len(content.data)/4 {
+				content.err = errVoxTruncated
+				break
+			}
+			voxels := content.bytes(n * 4)
+			model := VoxModel{Size: size, Voxels: make([]VoxVoxel, 0, n)}
+			for i := 0; i+3 < len(voxels); i += 4 {
+				model.Voxels = append(model.Voxels, VoxVoxel{
+					X: voxels[i], Y: voxels[i+1], Z: voxels[i+2], ColorIndex: voxels[i+3],
+				})
+			}
+			scene.Models = append(scene.Models, model)
+		case "RGBA":
+			rgba := content.bytes(256 * 4)
+			// Color index i is stored at entry i-1, the last entry is unused
+			for i := 0; i+1 < 256 && rgba != nil; i++ {
+				scene.Palette[i+1] = color.RGBA{rgba[i*4], rgba[i*4+1], rgba[i*4+2], rgba[i*4+3]}
+			}
+		case "nTRN":
+			node := &voxNode{kind: id, rotation: identityRotation()}
+			nodeID := content.int32()
+			content.dict()
+			node.children = []int{content.int32()}
+			content.int32() // reserved
+			content.int32() // layer
+			if content.int32() > 0 {
+				frame := content.dict()
+				if rot, ok := frame["_r"]; ok {
+					node.rotation, err = parseVoxRotation(rot)
+					if err != nil {
+						return nil, err
+					}
+				}
+				if t, ok := frame["_t"]; ok {
+					node.translation, err = parseVoxTranslation(t)
+					if err != nil {
+						return nil, err
+					}
+				}
+			}
+			nodes[nodeID] = node
+		case "nGRP":
+			node := &voxNode{kind: id}
+			nodeID := content.int32()
+			content.dict()
+			n := content.int32()
+			for i := 0; i < n && content.err == nil; i++ {
+				node.children = append(node.children, content.int32())
+			}
+			nodes[nodeID] = node
+		case "nSHP":
+			node := &voxNode{kind: id}
+			nodeID := content.int32()
+			content.dict()
+			n := content.int32()
+			for i := 0; i < n && content.err == nil; i++ {
+				node.models = append(node.models, content.int32())
+				content.dict()
+			}
+			nodes[nodeID] = node
+		}
+
+		if content.err != nil {
+			return nil, fmt.Errorf("%s chunk: %w", id, content.err)
+		}
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	if _, ok := nodes[0]; ok {
+		err := scene.walk(nodes, 0, identityRotation(), [3]int{}, make(map[int]bool))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return scene, nil
+}
+
+// walk accumulates the transforms down the scene graph into instances
+func (s *VoxScene) walk(nodes map[int]*voxNode, id int, rotation [3][3]int, translation [3]int, visited map[int]bool) error {
+	node, ok := nodes[id]
+	if !ok {
+		return fmt.Errorf("vox: missing scene node %d", id)
+	}
+	if visited[id] {
+		return fmt.Errorf("vox: scene node %d is in a cycle", id)
+	}
+	visited[id] = true
+	defer delete(visited, id)
+
+	if node.kind == "nTRN" {
+		translation = addVec(translation, rotateVec(rotation, node.translation))
+		rotation = mulRotation(rotation, node.rotation)
+	}
+
+	for _, model := range node.models {
+		if model < 0 || model >= len(s.Models) {
+			return fmt.Errorf("vox: shape references missing model %d", model)
+		}
+		s.Instances = append(s.Instances, VoxInstance{
+			Model:       model,
+			Rotation:    rotation,
+			Translation: translation,
+		})
+	}
+
+	for _, child := range node.children {
+		if err := s.walk(nodes, child, rotation, translation, visited); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Voxels calls fn for every voxel in the scene with its position converted
+// to world axes, where .vox z up becomes y up
+func (s *VoxScene) Voxels(fn func(pos BlockPos, c color.RGBA)) {
+	place := func(model VoxModel, transform func([3]int) [3]int) {
+		for _, v := range model.Voxels {
+			p := transform([3]int{int(v.X), int(v.Y), int(v.Z)})
+			fn(BlockPos{X: p[0], Y: p[2], Z: -p[1]}, s.Palette[v.ColorIndex])
+		}
+	}
+
+	if len(s.Instances) == 0 {
+		for _, model := range s.Models {
+			place(model, func(p [3]int) [3]int { return p })
+		}
+		return
+	}
+
+	for _, instance := range s.Instances {
+		model := s.Models[instance.Model]
+		pivot := [3]int{model.Size[0] / 2, model.Size[1] / 2, model.Size[2] / 2}
+
+		place(model, func(p [3]int) [3]int {
+			p = [3]int{p[0] - pivot[0], p[1] - pivot[1], p[2] - pivot[2]}
+			return addVec(rotateVec(instance.Rotation, p), instance.Translation)
+		})
+	}
+}
+
+// ImportVox stamps the scene into the world with its origin at the position.
+// mapColor picks the VoxelType for each palette color, and may return air to
+// skip a voxel. World.Blocks.NearestType is a good default
+func (w *World) ImportVox(scene *VoxScene, origin BlockPos, mapColor func(color.RGBA) VoxelType) {
+	scene.Voxels(func(pos BlockPos, c color.RGBA) {
+		t := mapColor(c)
+		if t == air {
+			return
+		}
+		w.SetVoxel(BlockPos{X: origin.X + pos.X, Y: origin.Y + pos.Y, Z: origin.Z + pos.Z}, t)
+	})
+}
+
+// parseVoxRotation unpacks a rotation byte. Bits 0-1 and 2-3 are the column
+// of the non zero entry in the first and second rows, and bits 4-6 are the
+// signs of each row
+func parseVoxRotation(s string) ([3][3]int, error) {
+	var m [3][3]int
+
+	packed, err := strconv.Atoi(s)
+	if err != nil {
+		return m, fmt.Errorf("vox: bad rotation %q", s)
+	}
+
+	first, second := packed&3, (packed>>2)&3
+	if first > 2 || second > 2 || first == second {
+		return m, fmt.Errorf("vox: bad rotation %q", s)
+	}
+	third := 3 - first - second
+
+	for row, col := range [3]int{first, second, third} {
+		m[row][col] = 1
+		if packed&(1<<(4+row)) != 0 {
+			m[row][col] = -1
+		}
+	}
+	return m, nil
+}
+
+func parseVoxTranslation(s string) ([3]int, error) {
+	var t [3]int
+
+	fields := strings.Fields(s)
+	if len(fields) != 3 {
+		return t, fmt.Errorf("vox: bad translation %q", s)
+	}
+	for i, field := range fields {
+		v, err := strconv.Atoi(field)
+		if err != nil {
+			return t, fmt.Errorf("vox: bad translation %q", s)
+		}
+		t[i] = v
+	}
+	return t, nil
+}
+
+func identityRotation() [3][3]int {
+	return [3][3]int{{1, 0, 0}, {0, 1, 0}, {0, 0, 1}}
+}
+
+func mulRotation(a, b [3][3]int) [3][3]int {
+	var m [3][3]int
+	for i := range 3 {
+		for j := range 3 {
+			for k := range 3 {
+				m[i][j] += a[i][k] * b[k][j]
+			}
+		}
+	}
+	return m
+}
+
+func rotateVec(m [3][3]int, v [3]int) [3]int {
+	var r [3]int
+	for i := range 3 {
+		r[i] = m[i][0]*v[0] + m[i][1]*v[1] + m[i][2]*v[2]
+	}
+	return r
+}
+
+func addVec(a, b [3]int) [3]int {
+	return [3]int{a[0] + b[0], a[1] + b[1], a[2] + b[2]}
+}
+
+// defaultVoxPalette is the palette used by files without an RGBA chunk: a
+// 6x6x6 color cube followed by red, green, blue and gray ramps
+func defaultVoxPalette() [256]color.RGBA {
+	var palette [256]color.RGBA
+
+	i := 1
+	cube := []uint8{0xff, 0xcc, 0x99, 0x66, 0x33, 0x00}
+	for _, r := range cube {
+		for _, g := range cube {
+			for _, b := range cube {
+				if r == 0 && g == 0 && b == 0 {
+					continue
+				}
+				palette[i] = color.RGBA{r, g, b, 255}
+				i++
+			}
+		}
+	}
+
+	ramp := []uint8{0xee, 0xdd, 0xbb, 0xaa, 0x88, 0x77, 0x55, 0x44, 0x22, 0x11}
+	for channel := range 4 {
+		for _, v := range ramp {
+			c := color.RGBA{A: 255}
+			switch channel {
+			case 0:
+				c.R = v
+			case 1:
+				c.G = v
+			case 2:
+				c.B = v
+			case 3:
+				c.R, c.G, c.B = v, v, v
+			}
+			palette[i] = c
+			i++
+		}
+	}
+
+	return palette
+}
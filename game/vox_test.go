@@ -0,0 +1,329 @@
+package game
+
+import (
+	"bytes"
+	"errors"
+	"image/color"
+	"math"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// voxFile wraps the chunks in a MAIN chunk with the file header
+func voxFile(chunks ...func(*voxWriter)) []byte {
+	var children voxWriter
+	for _, chunk := range chunks {
+		chunk(&children)
+	}
+
+	var w voxWriter
+	w.WriteString("VOX ")
+	w.int32(150)
+	w.WriteString("MAIN")
+	w.int32(0)
+	w.int32(children.Len())
+	w.Write(children.Bytes())
+	return w.Bytes()
+}
+
+func voxSize(x, y, z int) func(*voxWriter) {
+	return func(w *voxWriter) {
+		w.chunk("SIZE", func(c *voxWriter) {
+			c.int32(x)
+			c.int32(y)
+			c.int32(z)
+		})
+	}
+}
+
+func voxXYZI(voxels ...VoxVoxel) func(*voxWriter) {
+	return func(w *voxWriter) {
+		w.chunk("XYZI", func(c *voxWriter) {
+			c.int32(len(voxels))
+			for _, v := range voxels {
+				c.Write([]byte{v.X, v.Y, v.Z, v.ColorIndex})
+			}
+		})
+	}
+}
+
+// voxTransform is an nTRN node, frame holds its _r and _t if any
+func voxTransform(id, child int, frame ...string) func(*voxWriter) {
+	return func(w *voxWriter) {
+		w.chunk("nTRN", func(c *voxWriter) {
+			c.int32(id)
+			c.dict()
+			c.int32(child)
+			c.int32(-1) // reserved
+			c.int32(-1) // layer
+			c.int32(1)
+			c.dict(frame...)
+		})
+	}
+}
+
+func voxGroup(id int, children ...int) func(*voxWriter) {
+	return func(w *voxWriter) {
+		w.chunk("nGRP", func(c *voxWriter) {
+			c.int32(id)
+			c.dict()
+			c.int32(len(children))
+			for _, child := range children {
+				c.int32(child)
+			}
+		})
+	}
+}
+
+func voxShape(id, model int) func(*voxWriter) {
+	return func(w *voxWriter) {
+		w.chunk("nSHP", func(c *voxWriter) {
+			c.int32(id)
+			c.dict()
+			c.int32(1)
+			c.int32(model)
+			c.dict()
+		})
+	}
+}
+
+func TestParseVoxModel(t *testing.T) {
+	voxels := []VoxVoxel{{0, 0, 0, 1}, {1, 2, 3, 2}, {3, 0, 1, 255}}
+	data := voxFile(voxSize(4, 3, 5), voxXYZI(voxels...))
+
+	scene, err := ParseVox(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(scene.Models) != 1 || len(scene.Instances) != 0 {
+		t.Fatalf("got %d models and %d instances, want 1 model without instances", len(scene.Models), len(scene.Instances))
+	}
+	if got := scene.Models[0]; got.Size != [3]int{4, 3, 5} || !slices.Equal(got.Voxels, voxels) {
+		t.Errorf("model = %+v", got)
+	}
+
+	// Without an RGBA chunk the default palette is used
+	if got, want := scene.Palette[1], (color.RGBA{255, 255, 255, 255}); got != want {
+		t.Errorf("default palette[1] = %v, want %v", got, want)
+	}
+	if got, want := scene.Palette[255], (color.RGBA{0x11, 0x11, 0x11, 255}); got != want {
+		t.Errorf("default palette[255] = %v, want %v", got, want)
+	}
+
+	// Models without a scene graph sit at the origin, z up becomes y up
+	var got []BlockPos
+	scene.Voxels(func(pos BlockPos, c color.RGBA) { got = append(got, pos) })
+	want := []BlockPos{{0, 0, 0}, {1, 3, -2}, {3, 1, 0}}
+	if !slices.Equal(got, want) {
+		t.Errorf("Voxels at %v, want %v", got, want)
+	}
+}
+
+func TestParseVoxPalette(t *testing.T) {
+	rgba := func(w *voxWriter) {
+		w.chunk("RGBA", func(c *voxWriter) {
+			for i := range 256 {
+				c.Write([]byte{uint8(i), uint8(i + 1), uint8(i + 2), 255})
+			}
+		})
+	}
+	scene, err := ParseVox(bytes.NewReader(voxFile(voxSize(1, 1, 1), voxXYZI(VoxVoxel{0, 0, 0, 1}), rgba)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Entry i of the chunk is color index i+1
+	for _, i := range []int{1, 2, 100, 255} {
+		if got, want := scene.Palette[i], (color.RGBA{uint8(i - 1), uint8(i), uint8(i + 1), 255}); got != want {
+			t.Errorf("palette[%d] = %v, want %v", i, got, want)
+		}
+	}
+	if scene.Palette[0] != (color.RGBA{}) {
+		t.Errorf("palette[0] = %v, want unused", scene.Palette[0])
+	}
+}
+
+func TestParseVoxSceneGraph(t *testing.T) {
+	// _r 33 turns x into -y and y into x: rows (0 1 0), (-1 0 0), (0 0 1)
+	data := voxFile(
+		voxSize(3, 3, 3), voxXYZI(VoxVoxel{2, 1, 1, 1}),
+		voxSize(1, 1, 1), voxXYZI(VoxVoxel{0, 0, 0, 2}),
+		voxTransform(0, 1, "_r", "33", "_t", "10 20 30"),
+		voxGroup(1, 2, 4),
+		voxTransform(2, 3, "_t", "1 0 0"),
+		voxShape(3, 0),
+		voxTransform(4, 5),
+		voxShape(5, 1),
+	)
+
+	scene, err := ParseVox(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rotation := [3][3]int{{0, 1, 0}, {-1, 0, 0}, {0, 0, 1}}
+	want := []VoxInstance{
+		// The child translation is rotated by the parent
+		{Model: 0, Rotation: rotation, Translation: [3]int{10, 19, 30}},
+		{Model: 1, Rotation: rotation, Translation: [3]int{10, 20, 30}},
+	}
+	if !slices.Equal(scene.Instances, want) {
+		t.Fatalf("instances = %+v, want %+v", scene.Instances, want)
+	}
+
+	// Voxels rotate around the model's center before moving into place
+	got := make(map[BlockPos]color.RGBA)
+	scene.Voxels(func(pos BlockPos, c color.RGBA) { got[pos] = c })
+	if len(got) != 2 {
+		t.Fatalf("got voxels %v, want 2", got)
+	}
+	for pos, index := range map[BlockPos]int{{10, 30, -18}: 1, {10, 30, -20}: 2} {
+		if c, ok := got[pos]; !ok || c != scene.Palette[index] {
+			t.Errorf("voxel at %v = %v, %v, want palette color %d", pos, c, ok, index)
+		}
+	}
+}
+
+func TestParseVoxRotation(t *testing.T) {
+	tests := map[string][3][3]int{
+		"4":   {{1, 0, 0}, {0, 1, 0}, {0, 0, 1}},
+		"33":  {{0, 1, 0}, {-1, 0, 0}, {0, 0, 1}},
+		"104": {{1, 0, 0}, {0, 0, -1}, {0, -1, 0}},
+		"98":  {{0, 0, 1}, {-1, 0, 0}, {0, -1, 0}},
+	}
+	for s, want := range tests {
+		got, err := parseVoxRotation(s)
+		if err != nil || got != want {
+			t.Errorf("parseVoxRotation(%q) = %v, %v, want %v", s, got, err, want)
+		}
+	}
+
+	for _, s := range []string{"", "x", "0", "3", "5", "15"} {
+		if _, err := parseVoxRotation(s); err == nil {
+			t.Errorf("parseVoxRotation(%q) didn't fail", s)
+		}
+	}
+}
+
+func TestParseVoxBadSceneGraph(t *testing.T) {
+	model := []func(*voxWriter){voxSize(1, 1, 1), voxXYZI(VoxVoxel{0, 0, 0, 1})}
+	tests := map[string]struct {
+		nodes []func(*voxWriter)
+		err   string
+	}{
+		"cycle": {
+			[]func(*voxWriter){voxTransform(0, 1), voxGroup(1, 2), voxTransform(2, 0)},
+			"cycle",
+		},
+		"self": {
+			[]func(*voxWriter){voxTransform(0, 0)},
+			"cycle",
+		},
+		"missing node": {
+			[]func(*voxWriter){voxTransform(0, 7)},
+			"missing scene node 7",
+		},
+		"missing model": {
+			[]func(*voxWriter){voxTransform(0, 1), voxShape(1, 3)},
+			"missing model 3",
+		},
+		"bad rotation": {
+			[]func(*voxWriter){voxTransform(0, 1, "_r", "3"), voxShape(1, 0)},
+			"bad rotation",
+		},
+		"bad translation": {
+			[]func(*voxWriter){voxTransform(0, 1, "_t", "1 2"), voxShape(1, 0)},
+			"bad translation",
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := ParseVox(bytes.NewReader(voxFile(append(model, test.nodes...)...)))
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("ParseVox error = %v, want one mentioning %q", err, test.err)
+			}
+		})
+	}
+
+	// A node reachable along two paths isn't a cycle
+	data := voxFile(append(model, voxGroup(0, 1, 1), voxShape(1, 0))...)
+	scene, err := ParseVox(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(scene.Instances) != 2 {
+		t.Errorf("shared node gave %d instances, want 2", len(scene.Instances))
+	}
+}
+
+func TestParseVoxVoxelCount(t *testing.T) {
+	tests := []struct {
+		name      string
+		count     int
+		voxels    int
+		truncated bool
+	}{
+		{name: "negative", count: -1, voxels: 1},
+		{name: "most negative", count: math.MinInt32, voxels: 0},
+		{name: "one short", count: 3, voxels: 2, truncated: true},
+		{name: "huge", count: math.MaxInt32, voxels: 1, truncated: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			xyzi := func(w *voxWriter) {
+				w.chunk("XYZI", func(c *voxWriter) {
+					c.int32(tt.count)
+					for range tt.voxels {
+						c.Write([]byte{0, 0, 0, 1})
+					}
+				})
+			}
+
+			_, err := ParseVox(bytes.NewReader(voxFile(voxSize(1, 1, 1), xyzi)))
+			if err == nil || !strings.HasPrefix(err.Error(), "XYZI chunk: ") {
+				t.Fatalf("ParseVox = %v, want an XYZI chunk error", err)
+			}
+			if got := errors.Is(err, errVoxTruncated); got != tt.truncated {
+				t.Errorf("ParseVox = %v, errors.Is(errVoxTruncated) = %v, want %v", err, got, tt.truncated)
+			}
+		})
+	}
+}
+
+func TestParseVoxTruncated(t *testing.T) {
+	data := voxFile(
+		voxSize(2, 2, 2), voxXYZI(VoxVoxel{0, 0, 0, 1}, VoxVoxel{1, 1, 1, 2}),
+		voxTransform(0, 1, "_t", "1 2 3"), voxShape(1, 0),
+	)
+	if _, err := ParseVox(bytes.NewReader(data)); err != nil {
+		t.Fatal(err)
+	}
+
+	// Every prefix of the file is cut off somewhere inside it
+	for n := range len(data) {
+		_, err := ParseVox(bytes.NewReader(data[:n]))
+		if err == nil {
+			t.Fatalf("ParseVox of the first %d of %d bytes didn't fail", n, len(data))
+		}
+		if n >= 4 && !errors.Is(err, errVoxTruncated) {
+			t.Errorf("ParseVox of the first %d bytes = %v, want errVoxTruncated", n, err)
+		}
+	}
+
+	// Chunk sizes that agree with each other but not with the content
+	short := func(w *voxWriter) {
+		w.chunk("XYZI", func(c *voxWriter) {
+			c.int32(10)
+			c.Write([]byte{0, 0, 0, 1})
+		})
+	}
+	if _, err := ParseVox(bytes.NewReader(voxFile(voxSize(1, 1, 1), short))); !errors.Is(err, errVoxTruncated) {
+		t.Errorf("XYZI with missing voxels = %v, want errVoxTruncated", err)
+	}
+
+	if _, err := ParseVox(strings.NewReader("PNG whatever")); err == nil {
+		t.Error("ParseVox accepted a file without the magic")
+	}
+}
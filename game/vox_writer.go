@@ -0,0 +1,212 @@
+package game
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image/color"
+	"io"
+	"slices"
+)
+
+// voxMaxModelSize is the largest model a .vox file can hold along each axis
+const voxMaxModelSize = 256
+
+// voxWriter builds little endian .vox data
+type voxWriter struct {
+	bytes.Buffer
+}
+
+func (w *voxWriter) int32(v int) {
+	binary.Write(w, binary.LittleEndian, int32(v))
+}
+
+func (w *voxWriter) string(s string) {
+	w.int32(len(s))
+	w.WriteString(s)
+}
+
+func (w *voxWriter) dict(entries ...string) {
+	w.int32(len(entries) / 2)
+	for _, entry := range entries {
+		w.string(entry)
+	}
+}
+
+// chunk appends a chunk without children
+func (w *voxWriter) chunk(id string, content func(*voxWriter)) {
+	var c voxWriter
+	content(&c)
+
+	w.WriteString(id)
+	w.int32(c.Len())
+	w.int32(0)
+	w.Write(c.Bytes())
+}
+
+// exportVoxels returns a lookup of the world's voxels for exporting. Chunks
+// that aren't loaded are read from the store or generated, without being
+// added to the world, so exports don't depend on what is streamed in
+func (w *World) exportVoxels() func(pos BlockPos) VoxelType {
+	unloaded := make(map[ChunkCoord]*Chunk)
+	return func(pos BlockPos) VoxelType {
+		coord := pos.Chunk()
+		chunk, ok := w.Chunks[coord]
+		if !ok {
+			chunk, ok = unloaded[coord]
+			if !ok {
+				chunk = readOrGenerateChunk(coord, w.Store, w.Generator)
+				unloaded[coord] = chunk
+			}
+		}
+
+		x, y, z := pos.Local()
+		return chunk.voxelType(x, y, z)
+	}
+}
+
+// ExportVox writes the voxels in the box between the world positions,
+// inclusive, as a .vox file. Chunks in the box that aren't loaded are
+// exported as saved or generated. The palette holds one color per VoxelType
+// in the box, and boxes larger than a .vox model are split into several
+// models placed by the scene graph. Importing the file with ImportVox at the
+// box's minimum x and y and maximum z puts every voxel back where it was
+func (w *World) ExportVox(out io.Writer, from, to BlockPos) error {
+	lo := BlockPos{X: min(from.X, to.X), Y: min(from.Y, to.Y), Z: min(from.Z, to.Z)}
+	hi := BlockPos{X: max(from.X, to.X), Y: max(from.Y, to.Y), Z: max(from.Z, to.Z)}
+
+	// Size of the box in .vox space, where world y up is z up
+	size := [3]int{hi.X - lo.X + 1, hi.Z - lo.Z + 1, hi.Y - lo.Y + 1}
+
+	// voxelAt converts a .vox position in the box back to world space,
+	// inverting the conversion in VoxScene.Voxels
+	voxels := w.exportVoxels()
+	voxelAt := func(x, y, z int) VoxelType {
+		return voxels(BlockPos{X: lo.X + x, Y: lo.Y + z, Z: hi.Z - y})
+	}
+
+	var types []VoxelType
+	for x := range size[0] {
+		for y := range size[1] {
+			for z := range size[2] {
+				if t := voxelAt(x, y, z); t != air && !slices.Contains(types, t) {
+					types = append(types, t)
+				}
+			}
+		}
+	}
+	if len(types) > 255 {
+		return fmt.Errorf("vox: %d voxel types don't fit in a palette", len(types))
+	}
+	slices.Sort(types)
+
+	paletteIndex := make(map[VoxelType]uint8)
+	for i, t := range types {
+		paletteIndex[t] = uint8(i + 1)
+	}
+
+	var children voxWriter
+	var translations [][3]int
+
+	for x0 := 0; x0 < size[0]; x0 += voxMaxModelSize {
+		for y0 := 0; y0 < size[1]; y0 += voxMaxModelSize {
+			for z0 := 0; z0 < size[2]; z0 += voxMaxModelSize {
+				modelSize := [3]int{
+					min(voxMaxModelSize, size[0]-x0),
+					min(voxMaxModelSize, size[1]-y0),
+					min(voxMaxModelSize, size[2]-z0),
+				}
+
+				var voxels []byte
+				for x := range modelSize[0] {
+					for y := range modelSize[1] {
+						for z := range modelSize[2] {
+							t := voxelAt(x0+x, y0+y, z0+z)
+							if t == air {
+								continue
+							}
+							voxels = append(voxels, uint8(x), uint8(y), uint8(z), paletteIndex[t])
+						}
+					}
+				}
+
+				children.chunk("SIZE", func(c *voxWriter) {
+					c.int32(modelSize[0])
+					c.int32(modelSize[1])
+					c.int32(modelSize[2])
+				})
+				children.chunk("XYZI", func(c *voxWriter) {
+					c.int32(len(voxels) / 4)
+					c.Write(voxels)
+				})
+
+				// Models are positioned by their center
+				translations = append(translations, [3]int{
+					x0 + modelSize[0]/2,
+					y0 + modelSize[1]/2,
+					z0 + modelSize[2]/2,
+				})
+			}
+		}
+	}
+
+	// Scene graph: root transform, group, then a transform and shape for
+	// each model
+	children.chunk("nTRN", func(c *voxWriter) {
+		c.int32(0)
+		c.dict()
+		c.int32(1)
+		c.int32(-1)
+		c.int32(-1)
+		c.int32(1)
+		c.dict()
+	})
+	children.chunk("nGRP", func(c *voxWriter) {
+		c.int32(1)
+		c.dict()
+		c.int32(len(translations))
+		for i := range translations {
+			c.int32(2 + i*2)
+		}
+	})
+	for i, t := range translations {
+		children.chunk("nTRN", func(c *voxWriter) {
+			c.int32(2 + i*2)
+			c.dict()
+			c.int32(3 + i*2)
+			c.int32(-1)
+			c.int32(0)
+			c.int32(1)
+			c.dict("_t", fmt.Sprintf("%d %d %d", t[0], t[1], t[2]))
+		})
+		children.chunk("nSHP", func(c *voxWriter) {
+			c.int32(3 + i*2)
+			c.dict()
+			c.int32(1)
+			c.int32(i)
+			c.dict()
+		})
+	}
+
+	children.chunk("RGBA", func(c *voxWriter) {
+		// Entry i is color index i+1
+		for i := range 256 {
+			col := color.RGBA{}
+			if i < len(types) {
+				col = w.Blocks.Color(types[i])
+			}
+			c.Write([]byte{col.R, col.G, col.B, col.A})
+		}
+	})
+
+	var file voxWriter
+	file.WriteString("VOX ")
+	file.int32(150)
+	file.WriteString("MAIN")
+	file.int32(0)
+	file.int32(children.Len())
+	file.Write(children.Bytes())
+
+	_, err := out.Write(file.Bytes())
+	return err
+}
@@ -0,0 +1,54 @@
+package game
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestExportVoxRoundTrip(t *testing.T) {
+	world := newTestWorld(t, 0)
+	for _, pos := range []BlockPos{{-5, 1, -3}, {0, 0, 0}, {17, 3, 1}, {269, 2, -1}, {270, -2, 1}} {
+		world.SetVoxel(pos, dirt)
+	}
+	world.SetVoxel(BlockPos{X: 100, Y: 1, Z: 0}, grass)
+	world.SetVoxel(BlockPos{X: 3, Y: -1, Z: -2}, air)
+	loaded := len(world.Chunks)
+
+	// Wider than a .vox model, and mostly over chunks that aren't loaded
+	from, to := BlockPos{X: 270, Y: 3, Z: 1}, BlockPos{X: -5, Y: -2, Z: -3}
+	var file bytes.Buffer
+	if err := world.ExportVox(&file, from, to); err != nil {
+		t.Fatal(err)
+	}
+	if len(world.Chunks) != loaded {
+		t.Errorf("exporting loaded %d chunks into the world", len(world.Chunks)-loaded)
+	}
+
+	scene, err := ParseVox(&file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(scene.Models) != 2 {
+		t.Errorf("box 276 wide was split into %d models, want 2", len(scene.Models))
+	}
+
+	copied := newTestWorld(t, -100)
+	copied.ImportVox(scene, BlockPos{X: -5, Y: -2, Z: 1}, copied.Blocks.NearestType)
+
+	voxels := world.exportVoxels()
+	for x := -5; x <= 270; x++ {
+		for y := -2; y <= 3; y++ {
+			for z := -3; z <= 1; z++ {
+				pos := BlockPos{X: x, Y: y, Z: z}
+				if got, want := copied.GetVoxel(pos), voxels(pos); got != want {
+					t.Fatalf("voxel at %v = %v after the round trip, want %v", pos, got, want)
+				}
+			}
+		}
+	}
+
+	// The generated stone beneath y = 0 came through without being loaded
+	if got := copied.GetVoxel(BlockPos{X: 200, Y: -1, Z: 0}); got != stone {
+		t.Errorf("generated voxel = %v, want stone", got)
+	}
+}
@@ -4,18 +4,18 @@ import (
 	rl "github.com/gen2brain/raylib-go/raylib"
 )
 
-// Voxel represents a single cube in the world
-type Voxel struct {
-	Position rl.Vector3
-	Type     VoxelType
-}
-
-type VoxelType int
+// VoxelType is the kind of block a voxel is. Voxels are stored as just
+// their type, with the position implied by where they're stored. Types are
+// described by a BlockRegistry
+type VoxelType uint16
 
+// Types the terrain generators place, which must match the IDs in the
+// built in block definitions
 const (
-	_ VoxelType = iota
+	air VoxelType = iota // the zero value is empty space
 	grass
 	dirt
+	stone
 )
 
 var VoxelOutlineColor = rl.Black
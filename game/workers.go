@@ -0,0 +1,250 @@
+package game
+
+import (
+	"context"
+	"sync"
+)
+
+// defaultChunkQueueSize bounds how many chunks can wait for a worker
+const defaultChunkQueueSize = 64
+
+// chunkJob is a request for a worker to generate and mesh a chunk, or to
+// remesh a loaded one from a snapshot of its voxels
+type chunkJob struct {
+	coord    ChunkCoord
+	snapshot *voxelSnapshot
+	ctx      context.Context
+	cancel   context.CancelFunc
+}
+
+// chunkResult is a chunk finished by a worker
+type chunkResult struct {
+	job   *chunkJob
+	chunk *Chunk
+}
+
+// meshResult is a mesh rebuilt by a worker
+type meshResult struct {
+	job  *chunkJob
+	mesh ChunkMesh
+}
+
+// ChunkWorkers generates and meshes chunks on a bounded pool of goroutines
+// so the render loop doesn't stall. Jobs are started in the order they're
+// requested. Apart from Close, its methods must be called from a single
+// goroutine, normally the one running Engine.Run
+type ChunkWorkers struct {
+	generator ChunkGenerator
+	store     *RegionStore
+	blocks    *BlockRegistry
+	atlas     *BlockAtlas
+
+	jobs    chan *chunkJob
+	results chan chunkResult
+
+	// remeshes are taken before jobs so edits show up while chunks are
+	// still streaming in
+	remeshes chan *chunkJob
+	meshes   chan meshResult
+
+	// pending jobs by coord, only touched by the requesting goroutine
+	pending map[ChunkCoord]*chunkJob
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// Create a new pool of workers which accepts up to queueSize waiting jobs.
+// Chunks are read from the store if they were saved, which may be nil, and
+// meshed with textures from the atlas
+func NewChunkWorkers(generator ChunkGenerator, store *RegionStore, blocks *BlockRegistry, atlas *BlockAtlas, workers, queueSize int) *ChunkWorkers {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	cw := &ChunkWorkers{
+		generator: generator,
+		store:     store,
+		blocks:    blocks,
+		atlas:     atlas,
+		jobs:      make(chan *chunkJob, queueSize),
+		results:   make(chan chunkResult, queueSize),
+		remeshes:  make(chan *chunkJob, queueSize),
+		meshes:    make(chan meshResult, queueSize),
+		pending:   make(map[ChunkCoord]*chunkJob),
+		ctx:       ctx,
+		cancel:    cancel,
+	}
+
+	cw.wg.Add(workers)
+	for range workers {
+		go cw.work()
+	}
+
+	return cw
+}
+
+func (cw *ChunkWorkers) work() {
+	defer cw.wg.Done()
+
+	for {
+		select {
+		case <-cw.ctx.Done():
+			return
+		case job := <-cw.remeshes:
+			cw.remesh(job)
+			continue
+		default:
+		}
+
+		select {
+		case <-cw.ctx.Done():
+			return
+		case job := <-cw.remeshes:
+			cw.remesh(job)
+		case job := <-cw.jobs:
+			cw.generate(job)
+		}
+	}
+}
+
+func (cw *ChunkWorkers) generate(job *chunkJob) {
+	if job.ctx.Err() != nil {
+		return
+	}
+
+	chunk := readOrGenerateChunk(job.coord, cw.store, cw.generator)
+	if job.ctx.Err() != nil {
+		return
+	}
+	chunk.remesh(chunk.voxelType, cw.blocks, cw.atlas)
+
+	select {
+	case cw.results <- chunkResult{job: job, chunk: chunk}:
+	case <-job.ctx.Done():
+	}
+}
+
+func (cw *ChunkWorkers) remesh(job *chunkJob) {
+	if job.ctx.Err() != nil {
+		return
+	}
+
+	mesh := BuildGreedyMesh(job.snapshot.voxelType, cw.blocks, cw.atlas)
+
+	select {
+	case cw.meshes <- meshResult{job: job, mesh: mesh}:
+	case <-job.ctx.Done():
+	}
+}
+
+// Request queues the chunk for generation. It returns false without
+// blocking if the queue is full, so callers should try again later
+func (cw *ChunkWorkers) Request(coord ChunkCoord) bool {
+	if _, ok := cw.pending[coord]; ok {
+		return true
+	}
+
+	ctx, cancel := context.WithCancel(cw.ctx)
+	job := &chunkJob{coord: coord, ctx: ctx, cancel: cancel}
+
+	select {
+	case cw.jobs <- job:
+		cw.pending[coord] = job
+		return true
+	default:
+		cancel()
+		return false
+	}
+}
+
+// Remesh queues a loaded chunk to be remeshed from the snapshot of its
+// voxels, replacing any remesh of it still pending. It returns false
+// without blocking if the queue is full, so callers should try again later
+func (cw *ChunkWorkers) Remesh(coord ChunkCoord, snapshot *voxelSnapshot) bool {
+	ctx, cancel := context.WithCancel(cw.ctx)
+	job := &chunkJob{coord: coord, snapshot: snapshot, ctx: ctx, cancel: cancel}
+
+	select {
+	case cw.remeshes <- job:
+		cw.Cancel(coord)
+		cw.pending[coord] = job
+		return true
+	default:
+		cancel()
+		return false
+	}
+}
+
+// Pending reports whether the chunk has been requested but not completed
+func (cw *ChunkWorkers) Pending(coord ChunkCoord) bool {
+	_, ok := cw.pending[coord]
+	return ok
+}
+
+// Cancel abandons the request for the chunk if it hasn't completed yet
+func (cw *ChunkWorkers) Cancel(coord ChunkCoord) {
+	if job, ok := cw.pending[coord]; ok {
+		job.cancel()
+		delete(cw.pending, coord)
+	}
+}
+
+// CancelWhere cancels every pending request matching the predicate
+func (cw *ChunkWorkers) CancelWhere(cancel func(ChunkCoord) bool) {
+	for coord := range cw.pending {
+		if cancel(coord) {
+			cw.Cancel(coord)
+		}
+	}
+}
+
+// Completed returns the chunks finished since the last call without
+// blocking. Chunks that were cancelled are dropped
+func (cw *ChunkWorkers) Completed() []*Chunk {
+	var chunks []*Chunk
+
+	for {
+		select {
+		case result := <-cw.results:
+			// A cancelled job may have been requested again, in which case
+			// the newer job is the one we want
+			if cw.pending[result.job.coord] != result.job {
+				continue
+			}
+			delete(cw.pending, result.job.coord)
+			result.job.cancel()
+
+			chunks = append(chunks, result.chunk)
+		default:
+			return chunks
+		}
+	}
+}
+
+// Remeshed returns the meshes rebuilt since the last call by chunk, without
+// blocking. Meshes that were cancelled or replaced by a newer remesh are
+// dropped
+func (cw *ChunkWorkers) Remeshed() map[ChunkCoord]ChunkMesh {
+	meshes := make(map[ChunkCoord]ChunkMesh)
+
+	for {
+		select {
+		case result := <-cw.meshes:
+			if cw.pending[result.job.coord] != result.job {
+				continue
+			}
+			delete(cw.pending, result.job.coord)
+			result.job.cancel()
+
+			meshes[result.job.coord] = result.mesh
+		default:
+			return meshes
+		}
+	}
+}
+
+// Close stops the workers, abandoning any pending requests
+func (cw *ChunkWorkers) Close() {
+	cw.cancel()
+	cw.wg.Wait()
+}
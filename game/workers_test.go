@@ -0,0 +1,285 @@
+package game
+
+import (
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+// stubGenerator puts a single stone voxel in each chunk, recording the order
+// chunks were generated in. If release is set, each chunk waits for a value
+// from it before finishing, and started receives every coord as it begins
+type stubGenerator struct {
+	started chan ChunkCoord
+	release chan struct{}
+
+	mu        sync.Mutex
+	generated []ChunkCoord
+}
+
+func newBlockingGenerator() *stubGenerator {
+	return &stubGenerator{
+		started: make(chan ChunkCoord, 64),
+		release: make(chan struct{}),
+	}
+}
+
+func (g *stubGenerator) Generate(c *Chunk) {
+	if g.release != nil {
+		g.started <- c.Coord
+		<-g.release
+	}
+
+	g.mu.Lock()
+	g.generated = append(g.generated, c.Coord)
+	g.mu.Unlock()
+
+	c.setVoxel(0, 0, 0, stone)
+}
+
+func (g *stubGenerator) coords() []ChunkCoord {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return slices.Clone(g.generated)
+}
+
+// newTestWorkers starts a pool that is closed when the test ends
+func newTestWorkers(t *testing.T, generator ChunkGenerator, workers, queueSize int) *ChunkWorkers {
+	cw := NewChunkWorkers(generator, nil, DefaultBlockRegistry(), nil, workers, queueSize)
+	t.Cleanup(cw.Close)
+	return cw
+}
+
+// waitCompleted collects completed chunks until there are n of them
+func waitCompleted(t *testing.T, cw *ChunkWorkers, n int) []*Chunk {
+	t.Helper()
+
+	var chunks []*Chunk
+	deadline := time.Now().Add(5 * time.Second)
+	for len(chunks) < n {
+		if time.Now().After(deadline) {
+			t.Fatalf("only %d of %d chunks completed", len(chunks), n)
+		}
+		chunks = append(chunks, cw.Completed()...)
+		time.Sleep(time.Millisecond)
+	}
+	return chunks
+}
+
+// waitStarted waits for the blocking generator to start on the coord
+func waitStarted(t *testing.T, g *stubGenerator, want ChunkCoord) {
+	t.Helper()
+	select {
+	case coord := <-g.started:
+		if coord != want {
+			t.Fatalf("started generating %v, want %v", coord, want)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("never started generating %v", want)
+	}
+}
+
+func TestChunkWorkersOrder(t *testing.T) {
+	generator := &stubGenerator{}
+	cw := newTestWorkers(t, generator, 1, 16)
+
+	var want []ChunkCoord
+	for i := range 10 {
+		coord := ChunkCoord{X: int32(i), Z: int32(-i)}
+		if !cw.Request(coord) {
+			t.Fatalf("Request(%v) was refused", coord)
+		}
+		if !cw.Pending(coord) {
+			t.Fatalf("%v isn't pending after being requested", coord)
+		}
+		want = append(want, coord)
+	}
+
+	chunks := waitCompleted(t, cw, len(want))
+	for i, chunk := range chunks {
+		if chunk.Coord != want[i] {
+			t.Errorf("chunk %d completed is %v, want %v", i, chunk.Coord, want[i])
+		}
+		if chunk.voxelType(0, 0, 0) != stone || chunk.meshDirty || chunk.mesh.QuadCount() != 6 {
+			t.Errorf("chunk %v wasn't generated and meshed", chunk.Coord)
+		}
+		if cw.Pending(chunk.Coord) {
+			t.Errorf("%v is still pending after completing", chunk.Coord)
+		}
+	}
+	if got := generator.coords(); !slices.Equal(got, want) {
+		t.Errorf("generated %v, want %v", got, want)
+	}
+}
+
+func TestChunkWorkersDuplicateRequest(t *testing.T) {
+	generator := &stubGenerator{}
+	cw := newTestWorkers(t, generator, 1, 4)
+
+	coord := ChunkCoord{X: 3}
+	for range 3 {
+		if !cw.Request(coord) {
+			t.Fatal("repeated Request was refused")
+		}
+	}
+	waitCompleted(t, cw, 1)
+
+	time.Sleep(10 * time.Millisecond)
+	if extra := cw.Completed(); len(extra) != 0 {
+		t.Errorf("%d extra chunks completed for a repeated request", len(extra))
+	}
+	if got := generator.coords(); len(got) != 1 {
+		t.Errorf("generated %v, want one chunk", got)
+	}
+}
+
+func TestChunkWorkersCancel(t *testing.T) {
+	generator := newBlockingGenerator()
+	cw := newTestWorkers(t, generator, 1, 8)
+	t.Cleanup(func() { close(generator.release) })
+
+	// The only worker is busy with the first chunk while the rest queue up
+	busy, cancelled, kept := ChunkCoord{X: 0}, ChunkCoord{X: 1}, ChunkCoord{X: 2}
+	cw.Request(busy)
+	waitStarted(t, generator, busy)
+	cw.Request(cancelled)
+	cw.Request(kept)
+
+	cw.Cancel(cancelled)
+	if cw.Pending(cancelled) {
+		t.Fatal("cancelled chunk is still pending")
+	}
+
+	generator.release <- struct{}{}
+	waitStarted(t, generator, kept)
+	generator.release <- struct{}{}
+
+	chunks := waitCompleted(t, cw, 2)
+	if chunks[0].Coord != busy || chunks[1].Coord != kept {
+		t.Errorf("completed %v and %v, want %v and %v", chunks[0].Coord, chunks[1].Coord, busy, kept)
+	}
+	if got := generator.coords(); slices.Contains(got, cancelled) {
+		t.Errorf("cancelled chunk was generated: %v", got)
+	}
+}
+
+func TestChunkWorkersCancelWhileGenerating(t *testing.T) {
+	generator := newBlockingGenerator()
+	cw := newTestWorkers(t, generator, 1, 8)
+	t.Cleanup(func() { close(generator.release) })
+
+	coord := ChunkCoord{Y: 2}
+	cw.Request(coord)
+	waitStarted(t, generator, coord)
+
+	// Cancelling mid generation drops the result
+	cw.CancelWhere(func(c ChunkCoord) bool { return c.Y == 2 })
+	generator.release <- struct{}{}
+
+	// Requesting it again after cancelling gets a fresh chunk
+	cw.Request(coord)
+	waitStarted(t, generator, coord)
+	generator.release <- struct{}{}
+
+	if chunks := waitCompleted(t, cw, 1); len(chunks) != 1 || chunks[0].Coord != coord {
+		t.Fatalf("completed %d chunks, want just %v", len(chunks), coord)
+	}
+	time.Sleep(10 * time.Millisecond)
+	if extra := cw.Completed(); len(extra) != 0 {
+		t.Errorf("cancelled chunk completed too")
+	}
+}
+
+func TestChunkWorkersBackpressure(t *testing.T) {
+	const queueSize = 4
+	generator := newBlockingGenerator()
+	cw := newTestWorkers(t, generator, 1, queueSize)
+	released := false
+	t.Cleanup(func() {
+		if !released {
+			close(generator.release)
+		}
+	})
+
+	cw.Request(ChunkCoord{})
+	waitStarted(t, generator, ChunkCoord{})
+
+	// The queue fills up without Request ever blocking
+	for i := 1; i <= queueSize; i++ {
+		if !cw.Request(ChunkCoord{X: int32(i)}) {
+			t.Fatalf("Request %d was refused with room in the queue", i)
+		}
+	}
+	refused := ChunkCoord{X: queueSize + 1}
+	if cw.Request(refused) {
+		t.Fatal("Request was accepted with the queue full")
+	}
+	if cw.Pending(refused) {
+		t.Fatal("refused chunk is pending")
+	}
+
+	// Once the workers catch up there's room again
+	close(generator.release)
+	released = true
+	waitCompleted(t, cw, queueSize+1)
+	if !cw.Request(refused) {
+		t.Fatal("Request was refused after the queue drained")
+	}
+	waitCompleted(t, cw, 1)
+}
+
+func TestChunkWorkersRemesh(t *testing.T) {
+	generator := newBlockingGenerator()
+	cw := newTestWorkers(t, generator, 1, 8)
+	t.Cleanup(func() { close(generator.release) })
+
+	first, second := ChunkCoord{X: 1}, ChunkCoord{X: 2}
+	cw.Request(first)
+	waitStarted(t, generator, first)
+	cw.Request(second)
+
+	// The second remesh replaces the first, and both jump ahead of the
+	// second chunk waiting to be generated
+	var one, two voxelSnapshot
+	one[snapshotIndex(0, 0, 0)] = stone
+	two[snapshotIndex(0, 0, 0)], two[snapshotIndex(2, 0, 0)] = stone, stone
+
+	coord := ChunkCoord{Y: 1}
+	if !cw.Remesh(coord, &one) || !cw.Remesh(coord, &two) {
+		t.Fatal("Remesh was refused")
+	}
+
+	generator.release <- struct{}{}
+	waitStarted(t, generator, second)
+
+	meshes := cw.Remeshed()
+	mesh, ok := meshes[coord]
+	if len(meshes) != 1 || !ok {
+		t.Fatalf("remeshed %v, want only %v", meshes, coord)
+	}
+	if got := mesh.QuadCount(); got != 12 {
+		t.Errorf("mesh has %d quads, want 12 from the latest snapshot", got)
+	}
+	if cw.Pending(coord) {
+		t.Errorf("%v is still pending after being remeshed", coord)
+	}
+}
+
+func TestChunkWorkersClose(t *testing.T) {
+	cw := NewChunkWorkers(&stubGenerator{}, nil, DefaultBlockRegistry(), nil, 4, 16)
+	for i := range 16 {
+		cw.Request(ChunkCoord{X: int32(i)})
+	}
+
+	done := make(chan struct{})
+	go func() {
+		cw.Close()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Close didn't return")
+	}
+}
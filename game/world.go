@@ -1,6 +1,11 @@
 package game
 
 import (
+	"errors"
+	"fmt"
+	"log"
+	"runtime"
+
 	rl "github.com/gen2brain/raylib-go/raylib"
 )
 
@@ -9,29 +14,315 @@ var (
 	worldForward = rl.Vector3{X: 0, Y: 0, Z: 1}
 )
 
+// defaultWorldSections is how many chunk sections are stacked in a column,
+// giving a world 128 voxels tall
+const defaultWorldSections = 8
+
+// Default radii in chunks for streaming columns in and out around the camera
+const (
+	defaultViewRadius   = 6
+	defaultUnloadRadius = 8
+)
+
 // World contains all chunks
 type World struct {
-	Chunks map[ChunkID]*Chunk
+	Chunks map[ChunkCoord]*Chunk
+
+	// Sections is the number of chunks stacked vertically in each column
+	Sections int
+
+	// Generator fills in the voxels of new chunks
+	Generator ChunkGenerator
+
+	// Columns within ViewRadius chunks of the camera are loaded, and are
+	// unloaded once they're further than UnloadRadius
+	ViewRadius, UnloadRadius int
+
+	// Workers generate streamed chunks in the background
+	Workers *ChunkWorkers
+
+	// Store saves edited chunks, which are loaded from it instead of being
+	// generated. It may be nil to not persist the world
+	Store *RegionStore
+
+	// Blocks describes every VoxelType in the world
+	Blocks *BlockRegistry
+
+	// Atlas holds the block textures, which chunk meshes are textured from
+	Atlas *BlockAtlas
 }
 
-// Create a new world
-func NewWorld() *World {
+// Create a new world using the generator for its terrain, persisting
+// edited chunks to the store
+func NewWorld(generator ChunkGenerator, store *RegionStore, blocks *BlockRegistry, atlas *BlockAtlas) *World {
 	world := &World{
-		Chunks: make(map[ChunkID]*Chunk),
+		Chunks:       make(map[ChunkCoord]*Chunk),
+		Sections:     defaultWorldSections,
+		Generator:    generator,
+		ViewRadius:   defaultViewRadius,
+		UnloadRadius: defaultUnloadRadius,
+		Store:        store,
+		Blocks:       blocks,
+		Atlas:        atlas,
+		Workers: NewChunkWorkers(
+			generator,
+			store,
+			blocks,
+			atlas,
+			max(runtime.NumCPU()-1, 1),
+			defaultChunkQueueSize,
+		),
 	}
 
-	var chunks []Chunk
-	chunkGenRadius := 2
+	return world
+}
+
+// RenderDistance is how far away the corners of the furthest columns
+// streamed in at ViewRadius can be, which a camera's frustum should reach
+func (w *World) RenderDistance() float32 {
+	// Columns are measured between chunk origins, so the camera can be up
+	// to a chunk away from its own origin and the column's far corner a
+	// chunk past the column's
+	return float32(w.ViewRadius+2) * float32(chunkLength)
+}
 
-	for x := -1 * chunkGenRadius; x <= chunkGenRadius; x++ {
-		for z := -1 * chunkGenRadius; z <= chunkGenRadius; z++ {
-			chunks = append(chunks, NewChunk(16*x, 16*z))
+// Close stops the background workers and saves any edited chunks
+func (w *World) Close() error {
+	w.Workers.Close()
+	return w.Save()
+}
+
+// Save writes every edited chunk to the store
+func (w *World) Save() error {
+	var modified []*Chunk
+	for _, chunk := range w.Chunks {
+		if chunk.modified {
+			modified = append(modified, chunk)
 		}
 	}
+	return w.saveChunks(modified)
+}
 
+// saveChunks writes the chunks to the store, if there is one
+func (w *World) saveChunks(chunks []*Chunk) error {
+	if w.Store == nil || len(chunks) == 0 {
+		return nil
+	}
+
+	if err := w.Store.Save(chunks); err != nil {
+		return fmt.Errorf("saving chunks: %w", err)
+	}
 	for _, chunk := range chunks {
-		world.Chunks[chunk.ID] = &chunk
+		chunk.modified = false
 	}
+	return nil
+}
 
-	return world
+// readOrGenerateChunk loads the chunk from the store if it was saved, and
+// otherwise generates it from scratch
+func readOrGenerateChunk(coord ChunkCoord, store *RegionStore, generator ChunkGenerator) *Chunk {
+	if store != nil {
+		chunk, err := store.Load(coord)
+		if err == nil {
+			return chunk
+		}
+		if !errors.Is(err, ErrChunkNotSaved) {
+			log.Printf("regenerating chunk %s: %v", coord, err)
+		}
+	}
+
+	chunk := NewChunk(coord)
+	generator.Generate(&chunk)
+	return &chunk
+}
+
+// UpdateMeshes adds the chunks the workers have generated and the meshes
+// they've rebuilt, then sends every chunk whose voxels or neighbours have
+// changed back to them to be remeshed
+func (w *World) UpdateMeshes() {
+	for _, chunk := range w.Workers.Completed() {
+		w.addChunk(chunk)
+	}
+
+	for coord, mesh := range w.Workers.Remeshed() {
+		// The chunk may have been unloaded while it was being remeshed
+		if chunk, ok := w.Chunks[coord]; ok {
+			chunk.setMesh(mesh)
+		}
+	}
+
+	for _, chunk := range w.Chunks {
+		if !chunk.meshDirty {
+			continue
+		}
+		if !w.Workers.Remesh(chunk.Coord, w.snapshot(chunk)) {
+			// The rest are sent once the workers catch up
+			return
+		}
+		chunk.meshDirty = false
+	}
+}
+
+// addChunk adds a chunk generated in the background. Its mesh was built
+// without the neighbouring chunks, so it's remeshed if any are loaded to cull
+// the faces they share, and so are they
+func (w *World) addChunk(chunk *Chunk) {
+	if _, ok := w.Chunks[chunk.Coord]; ok {
+		// Already loaded synchronously, e.g. by SetVoxel
+		return
+	}
+	w.Chunks[chunk.Coord] = chunk
+
+	if w.dirtyNeighbours(chunk.Coord) {
+		chunk.meshDirty = true
+	}
+}
+
+// dirtyNeighbours marks the loaded chunks sharing a face with the coord for
+// remeshing, since the faces they share have changed. It reports whether
+// there were any
+func (w *World) dirtyNeighbours(coord ChunkCoord) bool {
+	var found bool
+	for _, neighbour := range coord.Neighbours() {
+		if n, ok := w.Chunks[neighbour]; ok {
+			n.meshDirty = true
+			found = true
+		}
+	}
+	return found
+}
+
+// loadChunk returns the chunk at the coord, generating it if it doesn't
+// exist yet
+func (w *World) loadChunk(coord ChunkCoord) *Chunk {
+	if chunk, ok := w.Chunks[coord]; ok {
+		return chunk
+	}
+
+	chunk := readOrGenerateChunk(coord, w.Store, w.Generator)
+	w.Chunks[coord] = chunk
+
+	// Neighbours can now cull the faces they share with the new chunk
+	w.dirtyNeighbours(coord)
+
+	return chunk
+}
+
+// GetVoxel returns the type of the voxel at the world position. Voxels in
+// chunks that aren't loaded are air
+func (w *World) GetVoxel(pos BlockPos) VoxelType {
+	chunk, ok := w.Chunks[pos.Chunk()]
+	if !ok {
+		return air
+	}
+
+	x, y, z := pos.Local()
+	return chunk.voxelType(x, y, z)
+}
+
+// Solid reports whether the voxel at the world position blocks movement
+func (w *World) Solid(pos BlockPos) bool {
+	return w.Blocks.Solid(w.GetVoxel(pos))
+}
+
+// SetVoxel sets the voxel at the world position, generating its chunk first
+// if needed. The chunk is remeshed, along with any neighbour that shares the
+// face of the voxel
+func (w *World) SetVoxel(pos BlockPos, t VoxelType) {
+	chunk := w.loadChunk(pos.Chunk())
+
+	x, y, z := pos.Local()
+	if chunk.voxelType(x, y, z) == t {
+		return
+	}
+	chunk.setVoxel(x, y, z, t)
+	chunk.modified = true
+
+	local := [3]int{x, y, z}
+	for i, offset := range faceOffsets {
+		// faceOffsets alternate +/- along x, y then z
+		axis := i / 2
+		edge := 0
+		if i%2 == 0 {
+			edge = chunkDims[axis] - 1
+		}
+		if local[axis] != edge {
+			continue
+		}
+
+		if neighbour, ok := w.Chunks[chunk.Coord.Neighbour(offset.X, offset.Y, offset.Z)]; ok {
+			neighbour.meshDirty = true
+		}
+	}
+}
+
+// voxelLookup returns a VoxelLookup for the chunk that reads voxels outside
+// of it from the neighbouring chunks. Missing neighbours are treated as air
+func (w *World) voxelLookup(c *Chunk) VoxelLookup {
+	return func(x, y, z int) VoxelType {
+		// Step into the neighbouring chunk along each axis
+		chunk := c
+		dx, x := chunkStep(x, int(chunkLength))
+		dy, y := chunkStep(y, int(chunkHeight))
+		dz, z := chunkStep(z, int(chunkLength))
+
+		if dx != 0 || dy != 0 || dz != 0 {
+			chunk = w.Chunks[c.Coord.Neighbour(int32(dx), int32(dy), int32(dz))]
+			if chunk == nil {
+				return air
+			}
+		}
+
+		return chunk.voxelType(x, y, z)
+	}
+}
+
+// voxelSnapshot is a copy of a chunk's voxels along with a one voxel border
+// from its neighbours, so it can be meshed away from the loaded chunks
+type voxelSnapshot [(int(chunkLength) + 2) * (int(chunkHeight) + 2) * (int(chunkLength) + 2)]VoxelType
+
+// snapshot copies everything a mesh of the chunk needs to see
+func (w *World) snapshot(c *Chunk) *voxelSnapshot {
+	lookup := w.voxelLookup(c)
+
+	var s voxelSnapshot
+	for y := -1; y <= int(chunkHeight); y++ {
+		for z := -1; z <= int(chunkLength); z++ {
+			for x := -1; x <= int(chunkLength); x++ {
+				s[snapshotIndex(x, y, z)] = lookup(x, y, z)
+			}
+		}
+	}
+	return &s
+}
+
+// voxelType is the VoxelLookup of the snapshot, treating anything beyond its
+// border as air
+func (s *voxelSnapshot) voxelType(x, y, z int) VoxelType {
+	if x < -1 || y < -1 || z < -1 ||
+		x > int(chunkLength) || y > int(chunkHeight) || z > int(chunkLength) {
+		return air
+	}
+
+	return (*s)[snapshotIndex(x, y, z)]
+}
+
+// snapshotIndex is the index of the chunk local position in a snapshot,
+// which starts one voxel outside the chunk
+func snapshotIndex(x, y, z int) int {
+	const length = int(chunkLength) + 2
+	return (x + 1) + (z+1)*length + (y+1)*length*length
+}
+
+// chunkStep returns which neighbouring chunk (-1, 0 or 1) a local position
+// one step outside of the chunk falls in, and the position within it
+func chunkStep(pos, size int) (int, int) {
+	switch {
+	case pos < 0:
+		return -1, pos + size
+	case pos >= size:
+		return 1, pos - size
+	default:
+		return 0, pos
+	}
 }
@@ -0,0 +1,320 @@
+package game
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// newTestWorld creates a world of stone up to height, without a store, that
+// is closed when the test ends
+func newTestWorld(t testing.TB, height int) *World {
+	world := NewWorld(FlatGenerator{Height: height, Type: stone}, nil, DefaultBlockRegistry(), nil)
+	closeWorld(t, world)
+	return world
+}
+
+// closeWorld closes the world when the test ends
+func closeWorld(t testing.TB, world *World) {
+	t.Cleanup(func() {
+		if err := world.Close(); err != nil {
+			t.Error(err)
+		}
+	})
+}
+
+// workerChunk builds the chunk the way ChunkWorkers does, meshed without
+// its neighbours
+func workerChunk(w *World, coord ChunkCoord) *Chunk {
+	chunk := readOrGenerateChunk(coord, w.Store, w.Generator)
+	chunk.remesh(chunk.voxelType, w.Blocks, w.Atlas)
+	return chunk
+}
+
+// waitMeshes runs UpdateMeshes until the workers have remeshed every chunk
+// that needed it
+func waitMeshes(t *testing.T, w *World) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		w.UpdateMeshes()
+		if len(w.Workers.pending) == 0 {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%d chunks were never remeshed", len(w.Workers.pending))
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestCulledMeshAcrossChunks(t *testing.T) {
+	// Each chunk is a single layer of grass, with 16 quads along each side
+	tests := []struct {
+		name       string
+		neighbours []ChunkCoord
+		quads      int
+	}{
+		{"no neighbours", nil, 2*16*16 + 4*16},
+		{"+x", []ChunkCoord{{X: 1}}, 2*16*16 + 3*16},
+		{"-z", []ChunkCoord{{Z: -1}}, 2*16*16 + 3*16},
+		{"all sides", []ChunkCoord{{X: 1}, {X: -1}, {Z: 1}, {Z: -1}}, 2 * 16 * 16},
+
+		// The section below is solid, the one above empty
+		{"below", []ChunkCoord{{Y: -1}}, 16*16 + 4*16},
+		{"above", []ChunkCoord{{Y: 1}}, 2*16*16 + 4*16},
+
+		// Only chunks sharing a face are looked at
+		{"diagonal", []ChunkCoord{{X: 1, Z: 1}, {X: -1, Y: -1}}, 2*16*16 + 4*16},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			generator := FlatGenerator{Height: 0, Type: grass}
+			world := &World{Chunks: make(map[ChunkCoord]*Chunk), Blocks: DefaultBlockRegistry()}
+			center := NewChunk(ChunkCoord{})
+			generator.Generate(&center)
+			world.Chunks[center.Coord] = &center
+			for _, coord := range test.neighbours {
+				chunk := NewChunk(coord)
+				generator.Generate(&chunk)
+				world.Chunks[chunk.Coord] = &chunk
+			}
+
+			mesh := BuildCulledMesh(world.voxelLookup(&center), world.Blocks, nil)
+			if got := mesh.QuadCount(); got != test.quads {
+				t.Errorf("%d quads, want %d", got, test.quads)
+			}
+		})
+	}
+}
+
+func TestWorldUsesGenerator(t *testing.T) {
+	world := NewWorld(FlatGenerator{Height: 3, Type: dirt}, nil, DefaultBlockRegistry(), nil)
+	closeWorld(t, world)
+
+	for _, coord := range []ChunkCoord{{}, {X: -3, Z: 7}} {
+		chunk := world.loadChunk(coord)
+		for y := range int(chunkHeight) {
+			want := air
+			if y <= 3 {
+				want = dirt
+			}
+			if got := chunk.voxelType(5, y, 9); got != want {
+				t.Fatalf("chunk %s has %v at y = %d, want %v", coord, got, y, want)
+			}
+		}
+	}
+}
+
+func TestAddChunkCullsSharedFaces(t *testing.T) {
+	world := newTestWorld(t, 32)
+
+	// On its own, both x sides of a solid chunk are visible
+	first := workerChunk(world, ChunkCoord{})
+	world.addChunk(first)
+	if first.meshDirty {
+		t.Error("chunk without neighbours was marked for remeshing")
+	}
+	if got := faceArea(&first.mesh, 0); got != 2*16*16 {
+		t.Fatalf("lone solid chunk has %v of x faces, want %v", got, 2*16*16)
+	}
+
+	second := workerChunk(world, ChunkCoord{X: 1})
+	world.addChunk(second)
+	if !first.meshDirty || !second.meshDirty {
+		t.Fatalf("meshDirty is %v and %v after adding a neighbour, want both set", first.meshDirty, second.meshDirty)
+	}
+
+	// Remeshing against each other drops the wall between them
+	waitMeshes(t, world)
+	for _, chunk := range []*Chunk{first, second} {
+		if got := faceArea(&chunk.mesh, 0); got != 16*16 {
+			t.Errorf("chunk %v has %v of x faces, want %v", chunk.Coord, got, 16*16)
+		}
+	}
+}
+
+func TestUpdateMeshes(t *testing.T) {
+	world := newTestWorld(t, 32)
+	first := world.loadChunk(ChunkCoord{})
+	second := world.loadChunk(ChunkCoord{X: 1})
+
+	waitMeshes(t, world)
+	for _, chunk := range []*Chunk{first, second} {
+		if chunk.meshDirty || chunk.meshUploaded {
+			t.Fatalf("chunk %v has meshDirty = %v, meshUploaded = %v after remeshing", chunk.Coord, chunk.meshDirty, chunk.meshUploaded)
+		}
+		if got := faceArea(&chunk.mesh, 0); got != 16*16 {
+			t.Errorf("chunk %v has %v of x faces, want %v", chunk.Coord, got, 16*16)
+		}
+	}
+
+	// Nothing changed, so neither chunk is remeshed again
+	first.meshUploaded, second.meshUploaded = true, true
+	world.UpdateMeshes()
+	if world.Workers.Pending(first.Coord) || world.Workers.Pending(second.Coord) {
+		t.Fatal("chunks were remeshed without any changes")
+	}
+
+	// Removing a voxel on the shared face remeshes both
+	world.SetVoxel(BlockPos{X: 15, Y: 4, Z: 4}, air)
+	waitMeshes(t, world)
+	if first.meshUploaded || second.meshUploaded {
+		t.Fatal("chunks weren't remeshed after a change")
+	}
+	if got := faceArea(&second.mesh, 0); got != 16*16+1 {
+		t.Errorf("neighbour has %v of x faces after the change, want %v", got, 16*16+1)
+	}
+}
+
+func TestUpdateMeshesDropsUnloadedChunks(t *testing.T) {
+	world := newTestWorld(t, 32)
+	chunk := world.loadChunk(ChunkCoord{})
+	world.UpdateMeshes()
+
+	// The chunk is unloaded before its mesh arrives
+	delete(world.Chunks, chunk.Coord)
+	waitMeshes(t, world)
+	if chunk.meshUploaded || chunk.mesh.QuadCount() != 0 {
+		t.Error("mesh was installed on a chunk that isn't loaded")
+	}
+}
+
+func TestSnapshotMatchesLookup(t *testing.T) {
+	world := newTestWorld(t, 20)
+	for _, coord := range []ChunkCoord{{}, {X: 1}, {Y: 1}, {Z: -1}} {
+		world.loadChunk(coord)
+	}
+	world.SetVoxel(BlockPos{X: 0, Y: 8, Z: 8}, air)
+
+	chunk := world.Chunks[ChunkCoord{}]
+	lookup, snapshot := world.voxelLookup(chunk), world.snapshot(chunk)
+	for x := -2; x <= int(chunkLength)+1; x++ {
+		for y := -2; y <= int(chunkHeight)+1; y++ {
+			for z := -2; z <= int(chunkLength)+1; z++ {
+				want := lookup(x, y, z)
+				if x < -1 || y < -1 || z < -1 ||
+					x > int(chunkLength) || y > int(chunkHeight) || z > int(chunkLength) {
+					want = air
+				}
+				if got := snapshot.voxelType(x, y, z); got != want {
+					t.Fatalf("snapshot has %v at (%d, %d, %d), want %v", got, x, y, z, want)
+				}
+			}
+		}
+	}
+}
+
+func TestSetVoxelAcrossChunks(t *testing.T) {
+	world := newTestWorld(t, 0)
+	for _, pos := range []BlockPos{{0, 0, 0}, {-1, 5, -1}, {15, 16, 15}, {-17, 40, 33}, {200, -20, -300}} {
+		world.SetVoxel(pos, dirt)
+		if got := world.GetVoxel(pos); got != dirt {
+			t.Errorf("GetVoxel(%v) = %v after setting dirt", pos, got)
+		}
+	}
+
+	if got := world.GetVoxel(BlockPos{X: 0, Y: 0, Z: 1}); got != stone {
+		t.Errorf("GetVoxel of the ground = %v, want stone", got)
+	}
+	if got := world.GetVoxel(BlockPos{X: 1000, Y: 3, Z: 1000}); got != air {
+		t.Errorf("unloaded voxel = %v, want air", got)
+	}
+}
+
+func TestSetVoxelDirtiesNeighbours(t *testing.T) {
+	tests := []struct {
+		name  string
+		pos   BlockPos
+		dirty []ChunkCoord
+	}{
+		{"inside", BlockPos{X: 8, Y: 8, Z: 8}, []ChunkCoord{{}}},
+		{"+x edge", BlockPos{X: 15, Y: 8, Z: 8}, []ChunkCoord{{}, {X: 1}}},
+		{"-y edge", BlockPos{X: 8, Y: 16, Z: 8}, []ChunkCoord{{Y: 1}, {}}},
+
+		// The section below the bottom of the world isn't loaded
+		{"corner", BlockPos{X: -1, Y: 0, Z: -16}, []ChunkCoord{{X: -1, Z: -1}, {Z: -1}, {X: -1, Z: -2}}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			world := newTestWorld(t, 0)
+			for x := -2; x <= 2; x++ {
+				for y := range 3 {
+					for z := -2; z <= 2; z++ {
+						world.loadChunk(ChunkCoord{X: int32(x), Y: int32(y), Z: int32(z)})
+					}
+				}
+			}
+			for _, chunk := range world.Chunks {
+				chunk.meshDirty = false
+			}
+
+			world.SetVoxel(test.pos, dirt)
+
+			var dirty []ChunkCoord
+			for coord, chunk := range world.Chunks {
+				if chunk.meshDirty {
+					dirty = append(dirty, coord)
+				}
+			}
+			if len(dirty) != len(test.dirty) {
+				t.Fatalf("chunks %v were marked for remeshing, want %v", dirty, test.dirty)
+			}
+			for _, coord := range test.dirty {
+				if chunk, ok := world.Chunks[coord]; !ok || !chunk.meshDirty {
+					t.Errorf("chunk %v wasn't marked for remeshing", coord)
+				}
+			}
+
+			// Setting the same type again changes nothing
+			world.Chunks[test.pos.Chunk()].meshDirty = false
+			world.SetVoxel(test.pos, dirt)
+			if world.Chunks[test.pos.Chunk()].meshDirty {
+				t.Error("setting a voxel to its own type marked the chunk for remeshing")
+			}
+		})
+	}
+}
+
+func TestWorldSavesEditedChunks(t *testing.T) {
+	store, err := NewRegionStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	edited, streamed := BlockPos{X: 3, Y: 20, Z: -4}, BlockPos{X: 40, Y: 20, Z: 0}
+	world := NewWorld(FlatGenerator{Height: 8, Type: stone}, store, DefaultBlockRegistry(), nil)
+	world.UnloadRadius = 1
+	world.SetVoxel(edited, dirt)
+	world.SetVoxel(streamed, grass)
+	world.loadChunk(ChunkCoord{X: -1})
+
+	// Streaming away from the second edit unloads and saves it
+	if err := world.Stream(streamCamera([2]int32{}, nil)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Load(streamed.Chunk()); err != nil {
+		t.Fatalf("chunk wasn't saved when it was unloaded: %v", err)
+	}
+	if _, err := store.Load(edited.Chunk()); !errors.Is(err, ErrChunkNotSaved) {
+		t.Fatalf("loaded chunk was saved before closing, err = %v", err)
+	}
+
+	// Closing saves the rest, but only the edited chunks
+	if err := world.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Load(ChunkCoord{X: -1}); !errors.Is(err, ErrChunkNotSaved) {
+		t.Errorf("unedited chunk was saved, err = %v", err)
+	}
+
+	reopened := NewWorld(FlatGenerator{Height: 8, Type: stone}, store, DefaultBlockRegistry(), nil)
+	closeWorld(t, reopened)
+	for pos, want := range map[BlockPos]VoxelType{edited: dirt, streamed: grass} {
+		reopened.loadChunk(pos.Chunk())
+		if got := reopened.GetVoxel(pos); got != want {
+			t.Errorf("GetVoxel(%v) = %v after reopening, want %v", pos, got, want)
+		}
+	}
+}